@@ -37,6 +37,9 @@ func (c *Counter) Val() uint64 {
 // counter is a thread-safe connection counter.
 var counter Counter
 
+// kemPool recycles the KEMs of the connections.
+var kemPool = oqs.NewKEMPool(64, 0)
+
 func main() {
 	if len(os.Args) == 1 {
 		fmt.Println("Usage: server_kem <port number> [KEM name (optional)]")
@@ -83,12 +86,12 @@ func handleConnection(conn net.Conn, kemName string) {
 		log.Fatal(errors.New("server cannot send the KEM name to the client"))
 	}
 
-	// Construct and initialize the KEM server
-	server := oqs.KeyEncapsulation{}
-	defer server.Clean() // clean up even in case of panic
-	if err := server.Init(kemName, nil); err != nil {
+	// Get an initialized KEM server from the pool
+	server, err := kemPool.Get(kemName)
+	if err != nil {
 		log.Fatal(err)
 	}
+	defer kemPool.Put(server) // return to the pool even in case of panic
 
 	// Read the public key sent by the client
 	clientPublicKey := make([]byte, server.Details().LengthPublicKey)
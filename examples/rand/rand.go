@@ -3,6 +3,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"runtime"
 
@@ -37,4 +38,12 @@ func main() {
 		}
 		fmt.Printf("%-18s% X\n", "OpenSSL: ", oqs.RandomBytes(32))
 	}
+
+	// oqs.Rand is an io.Reader that fills the caller's buffer through the
+	// currently selected algorithm
+	buf := make([]byte, 32)
+	if _, err := io.ReadFull(oqs.Rand, buf); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-18s% X\n", "oqs.Rand: ", buf)
 }
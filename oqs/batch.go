@@ -0,0 +1,154 @@
+package oqs
+
+/*
+#include <oqs/oqs.h>
+*/
+import "C"
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+/**************** Batch verification ****************/
+
+// batchPool holds the liboqs signature objects of the batch workers, so that
+// they are reused across batches.
+var batchPool = NewSigPool(runtime.GOMAXPROCS(0), 0)
+
+// VerifyItem is a single signature verification of VerifyBatch.
+type VerifyItem struct {
+	Message   []byte
+	Signature []byte
+	PublicKey []byte
+	Context   []byte // optional context string, see VerifyWithCtxStr
+}
+
+// BatchStats reports the aggregate figures of a batch verification.
+type BatchStats struct {
+	Items   int // number of verified items, excluding the cancelled ones
+	Valid   int // number of valid signatures
+	Workers int // number of parallel workers
+	Elapsed time.Duration
+}
+
+// Throughput returns the number of verified items per second.
+func (stats BatchStats) Throughput() float64 {
+	if stats.Elapsed <= 0 {
+		return 0
+	}
+	return float64(stats.Items) / stats.Elapsed.Seconds()
+}
+
+// VerifyBatch verifies many signatures in parallel with the algorithm of the
+// sig receiver, and returns one result per item: nil if the signature is
+// valid, an error wrapping ErrVerificationFailed if it is not, or a length or
+// context error as returned by Verify. The sig receiver only provides the
+// algorithm, it need not hold a secret key. See VerifyBatchContext.
+func (sig *Signature) VerifyBatch(items []VerifyItem) []error {
+	results, _ := sig.VerifyBatchContext(context.Background(), items)
+	return results
+}
+
+// VerifyBatchContext is like VerifyBatch, but stops early once ctx is done,
+// in which case the result of each item that was not verified is ctx.Err().
+// The items are distributed across runtime.GOMAXPROCS(0) workers at most, each
+// with its own liboqs signature object, recycled across batches by a
+// package-level SigPool, so that no liboqs object is created per item. It
+// also returns the aggregate statistics of the batch.
+func (sig *Signature) VerifyBatchContext(ctx context.Context,
+	items []VerifyItem,
+) ([]error, BatchStats) {
+	results := make([]error, len(items))
+	if sig.sig == nil {
+		for i := range results {
+			results[i] = newError("", "verify", ErrNotInitialized)
+		}
+		return results, BatchStats{}
+	}
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	stats := BatchStats{Workers: workers}
+	start := time.Now()
+
+	next := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker, err := batchPool.Get(sig.algDetails.Name)
+			if err == nil {
+				defer func() { _ = batchPool.Put(worker) }()
+			}
+			verified, valid := 0, 0
+			for i := range next {
+				if err != nil {
+					results[i] = err
+				} else {
+					results[i] = sig.verifyItem(worker.sig, &items[i])
+				}
+				verified++
+				if results[i] == nil {
+					valid++
+				}
+			}
+			mu.Lock()
+			stats.Items += verified
+			stats.Valid += valid
+			mu.Unlock()
+		}()
+	}
+
+	i := 0
+dispatch:
+	for ; i < len(items) && ctx.Err() == nil; i++ {
+		select {
+		case next <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(next)
+	for ; i < len(items); i++ {
+		results[i] = ctx.Err()
+	}
+	wg.Wait()
+
+	stats.Elapsed = time.Since(start)
+	return results, stats
+}
+
+// verifyItem verifies a single item of a batch with the liboqs signature
+// object of a worker.
+func (sig *Signature) verifyItem(worker *C.OQS_SIG, item *VerifyItem) error {
+	if len(item.Context) > 0 && !sig.algDetails.SigWithCtxSupport {
+		return newError(sig.algDetails.Name, "verify", ErrContextNotSupported)
+	}
+	if err := sig.checkVerifyArgs(item.Signature, item.PublicKey); err != nil {
+		return err
+	}
+
+	rv := C.OQS_SIG_verify_with_ctx_str(
+		worker,
+		bytesPtr(item.Message),
+		C.size_t(len(item.Message)),
+		bytesPtr(item.Signature),
+		C.size_t(len(item.Signature)),
+		bytesPtr(item.Context),
+		C.size_t(len(item.Context)),
+		bytesPtr(item.PublicKey),
+	)
+	if rv != C.OQS_SUCCESS {
+		return newError(sig.algDetails.Name, "verify", ErrVerificationFailed)
+	}
+	return nil
+}
+
+/**************** END Batch verification ****************/
@@ -1,13 +1,81 @@
 package oqs
 
-// C callbacks, DO NOT CHANGE
+// C callbacks: the RNG dispatch installed into liboqs, which serves the
+// per-thread WithRand readers and seeds before the selected RNG algorithm,
+// and the stateful secret key store callback
 
 /*
 #include <stdint.h>
 #include <stddef.h>
+#include <string.h>
+#include <oqs/oqs.h>
 void randAlgorithmPtr_cgo(uint8_t* random_array, size_t bytes_to_read) {
 	void randAlgorithmPtr(uint8_t*, size_t);
 	randAlgorithmPtr(random_array, bytes_to_read);
 }
+static _Thread_local uintptr_t readerRand_handle;
+static _Thread_local const uint8_t* seededRand_buf;
+static _Thread_local size_t seededRand_len;
+static _Thread_local int seededRand_overflow;
+static void (*selectedRand)(uint8_t*, size_t) = OQS_randombytes_system;
+OQS_STATUS selectRandAlgorithm_cgo(const char* algorithm) {
+	void (*f)(uint8_t*, size_t);
+	if (algorithm == NULL) {
+		f = randAlgorithmPtr_cgo;
+	} else if (strcmp(algorithm, "system") == 0) {
+		f = OQS_randombytes_system;
+#ifdef OQS_USE_OPENSSL
+	} else if (strcmp(algorithm, "OpenSSL") == 0) {
+		f = OQS_randombytes_openssl;
+#endif
+	} else {
+		return OQS_ERROR;
+	}
+	__atomic_store_n(&selectedRand, f, __ATOMIC_RELEASE);
+	return OQS_SUCCESS;
+}
+void setReaderRand_cgo(uintptr_t handle) {
+	readerRand_handle = handle;
+}
+void threadRandAlgorithm_cgo(uint8_t* random_array, size_t bytes_to_read) {
+	if (readerRand_handle != 0) {
+		void readerRandAlgorithm(uintptr_t, uint8_t*, size_t);
+		readerRandAlgorithm(readerRand_handle, random_array, bytes_to_read);
+		return;
+	}
+	if (seededRand_buf == NULL) {
+		__atomic_load_n(&selectedRand, __ATOMIC_ACQUIRE)(random_array,
+			bytes_to_read);
+		return;
+	}
+	if (bytes_to_read > seededRand_len) {
+		seededRand_overflow = 1;
+		memset(random_array, 0, bytes_to_read);
+		return;
+	}
+	memcpy(random_array, seededRand_buf, bytes_to_read);
+	seededRand_buf += bytes_to_read;
+	seededRand_len -= bytes_to_read;
+}
+OQS_STATUS sigKeypairFromSeed_cgo(const OQS_SIG* sig, uint8_t* public_key,
+	uint8_t* secret_key, const uint8_t* seed, size_t seed_len) {
+	seededRand_buf = seed;
+	seededRand_len = seed_len;
+	seededRand_overflow = 0;
+	OQS_STATUS rv = OQS_SIG_keypair(sig, public_key, secret_key);
+	if (seededRand_len != 0 || seededRand_overflow) {
+		rv = OQS_ERROR;
+	}
+	seededRand_buf = NULL;
+	seededRand_len = 0;
+	return rv;
+}
+OQS_STATUS storeSecretKey_cgo(uint8_t* sk_buf, size_t buf_len, void* context) {
+	OQS_STATUS storeSecretKey(uint8_t*, size_t, uintptr_t);
+	return storeSecretKey(sk_buf, buf_len, (uintptr_t)context);
+}
+void setStoreSecretKey_cgo(OQS_SIG_STFL_SECRET_KEY* sk, uintptr_t handle) {
+	OQS_SIG_STFL_SECRET_KEY_SET_store_cb(sk, storeSecretKey_cgo, (void*)handle);
+}
 */
 import "C"
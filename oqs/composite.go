@@ -0,0 +1,443 @@
+package oqs
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/asn1"
+	"io"
+	"math/big"
+)
+
+/**************** Composite signatures ****************/
+
+// Composite signatures follow draft-ietf-lamps-pq-composite-sigs-12: the
+// message representative is M' = Prefix || Label || len(ctx) || ctx || PH(M),
+// which both components sign, the ML-DSA component with Label as its context
+// string. Public keys, secret keys and signatures are the concatenations of
+// the ML-DSA and traditional components, the ML-DSA secret key being its
+// 32-byte seed.
+
+// compositePrefix is the composite signature domain separation prefix, i.e.
+// the ASCII string "CompositeAlgorithmSignatures2025".
+var compositePrefix = []byte("CompositeAlgorithmSignatures2025")
+
+// compositeSeedLength is the length of the ML-DSA seed ξ, which is the ML-DSA
+// component of a composite secret key.
+const compositeSeedLength = 32
+
+// compositeSigParams defines a composite signature algorithm, i.e. an ML-DSA
+// parameter set and a traditional algorithm. A nil curve denotes Ed25519,
+// otherwise tradHash is the hash of the ECDSA component. ph is the pre-hash
+// PH applied to the message.
+type compositeSigParams struct {
+	oid      asn1.ObjectIdentifier
+	label    string
+	mldsa    string
+	curve    elliptic.Curve
+	tradHash crypto.Hash
+	ph       crypto.Hash
+}
+
+// compositeSigs lists the composite signature algorithms, with the OIDs and
+// labels of draft-ietf-lamps-pq-composite-sigs-12.
+var compositeSigs = map[string]compositeSigParams{
+	"MLDSA44-Ed25519-SHA512": {
+		asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 39},
+		"COMPSIG-MLDSA44-Ed25519-SHA512",
+		"ML-DSA-44", nil, 0, crypto.SHA512,
+	},
+	"MLDSA44-ECDSA-P256-SHA256": {
+		asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 40},
+		"COMPSIG-MLDSA44-ECDSA-P256-SHA256",
+		"ML-DSA-44", elliptic.P256(), crypto.SHA256, crypto.SHA256,
+	},
+	"MLDSA65-ECDSA-P256-SHA512": {
+		asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 45},
+		"COMPSIG-MLDSA65-ECDSA-P256-SHA512",
+		"ML-DSA-65", elliptic.P256(), crypto.SHA256, crypto.SHA512,
+	},
+	"MLDSA65-ECDSA-P384-SHA512": {
+		asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 46},
+		"COMPSIG-MLDSA65-ECDSA-P384-SHA512",
+		"ML-DSA-65", elliptic.P384(), crypto.SHA384, crypto.SHA512,
+	},
+	"MLDSA65-Ed25519-SHA512": {
+		asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 48},
+		"COMPSIG-MLDSA65-Ed25519-SHA512",
+		"ML-DSA-65", nil, 0, crypto.SHA512,
+	},
+	"MLDSA87-ECDSA-P384-SHA512": {
+		asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, 49},
+		"COMPSIG-MLDSA87-ECDSA-P384-SHA512",
+		"ML-DSA-87", elliptic.P384(), crypto.SHA384, crypto.SHA512,
+	},
+}
+
+// SupportedCompositeSigs returns the list of composite signature algorithms.
+func SupportedCompositeSigs() []string {
+	return []string{
+		"MLDSA44-Ed25519-SHA512", "MLDSA44-ECDSA-P256-SHA256",
+		"MLDSA65-ECDSA-P256-SHA512", "MLDSA65-ECDSA-P384-SHA512",
+		"MLDSA65-Ed25519-SHA512", "MLDSA87-ECDSA-P384-SHA512",
+	}
+}
+
+// lookupCompositeSig returns the parameters of a composite algorithm.
+func lookupCompositeSig(algName, op string) (*compositeSigParams, error) {
+	params, ok := compositeSigs[algName]
+	if !ok {
+		return nil, newError(algName, op, ErrAlgorithmNotSupported)
+	}
+	if !IsSigEnabled(params.mldsa) {
+		return nil, newError(algName, op, ErrAlgorithmNotEnabled)
+	}
+	return &params, nil
+}
+
+// ecdhCurve returns the crypto/ecdh counterpart of an ECDSA curve.
+func ecdhCurve(curve elliptic.Curve) ecdh.Curve {
+	switch curve {
+	case elliptic.P256():
+		return ecdh.P256()
+	case elliptic.P384():
+		return ecdh.P384()
+	}
+	return nil
+}
+
+// messageRepresentative computes the composite message representative
+// M' = Prefix || Label || len(ctx) || ctx || PH(M).
+func (params *compositeSigParams) messageRepresentative(algName, op string,
+	message, context []byte,
+) ([]byte, error) {
+	if len(context) > 255 {
+		return nil, newError(algName, op, ErrContextNotSupported)
+	}
+	h := params.ph.New()
+	h.Write(message)
+	m := make([]byte, 0, len(compositePrefix)+len(params.label)+1+
+		len(context)+h.Size())
+	m = append(m, compositePrefix...)
+	m = append(m, params.label...)
+	m = append(m, byte(len(context)))
+	m = append(m, context...)
+	return h.Sum(m), nil
+}
+
+// CompositePublicKey is a composite signature public key, made of an ML-DSA
+// public key and a traditional (Ed25519 or ECDSA) public key. It implements
+// crypto.PublicKey.
+type CompositePublicKey struct {
+	algName string
+	params  *compositeSigParams
+	mldsa   []byte
+	trad    crypto.PublicKey
+}
+
+// ParseCompositePublicKey parses a composite public key of the composite
+// algorithm algName, i.e. the ML-DSA public key followed by the Ed25519
+// public key or the uncompressed ECDSA point.
+func ParseCompositePublicKey(algName string, publicKey []byte) (
+	*CompositePublicKey, error,
+) {
+	params, err := lookupCompositeSig(algName, "public key")
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := NewSignature(params.mldsa)
+	if err != nil {
+		return nil, err
+	}
+	lenMLDSA := verifier.Details().LengthPublicKey
+	verifier.Clean()
+	if len(publicKey) < lenMLDSA {
+		return nil, newError(algName, "public key", ErrInvalidPublicKeyLength)
+	}
+	mldsa, trad := publicKey[:lenMLDSA], publicKey[lenMLDSA:]
+	pub := &CompositePublicKey{
+		algName: algName,
+		params:  params,
+		mldsa:   append([]byte(nil), mldsa...),
+	}
+	if params.curve == nil {
+		if len(trad) != ed25519.PublicKeySize {
+			return nil, newError(algName, "public key",
+				ErrInvalidPublicKeyLength)
+		}
+		pub.trad = ed25519.PublicKey(append([]byte(nil), trad...))
+		return pub, nil
+	}
+	// crypto/ecdh validates that the point is on the curve.
+	if _, err := ecdhCurve(params.curve).NewPublicKey(trad); err != nil {
+		return nil, newError(algName, "public key", ErrInvalidPublicKey)
+	}
+	coordLength := (len(trad) - 1) / 2
+	pub.trad = &ecdsa.PublicKey{
+		Curve: params.curve,
+		X:     new(big.Int).SetBytes(trad[1 : 1+coordLength]),
+		Y:     new(big.Int).SetBytes(trad[1+coordLength:]),
+	}
+	return pub, nil
+}
+
+// Algorithm returns the composite algorithm name.
+func (pub *CompositePublicKey) Algorithm() string {
+	return pub.algName
+}
+
+// tradBytes returns the raw traditional public key.
+func (pub *CompositePublicKey) tradBytes() []byte {
+	if k, ok := pub.trad.(*ecdsa.PublicKey); ok {
+		ecdhKey, err := k.ECDH()
+		if err != nil {
+			return nil
+		}
+		return ecdhKey.Bytes()
+	}
+	return append([]byte(nil), pub.trad.(ed25519.PublicKey)...)
+}
+
+// Bytes returns the encoding of the composite public key, i.e. the ML-DSA
+// public key followed by the traditional public key.
+func (pub *CompositePublicKey) Bytes() ([]byte, error) {
+	return append(append([]byte(nil), pub.mldsa...), pub.tradBytes()...), nil
+}
+
+// Equal reports whether pub and x have the same algorithm and value.
+func (pub *CompositePublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*CompositePublicKey)
+	if !ok {
+		return false
+	}
+	return pub.algName == xx.algName &&
+		subtle.ConstantTimeCompare(pub.mldsa, xx.mldsa) == 1 &&
+		subtle.ConstantTimeCompare(pub.tradBytes(), xx.tradBytes()) == 1
+}
+
+// Verify verifies a composite signature of message, i.e. the ML-DSA signature
+// followed by the traditional signature, returning true if and only if both
+// component signatures are valid. opts may be nil, or a *SignerOpts carrying
+// a context string.
+func (pub *CompositePublicKey) Verify(message, signature []byte,
+	opts crypto.SignerOpts,
+) (bool, error) {
+	context, err := signerContext(pub.algName, "verify", opts)
+	if err != nil {
+		return false, err
+	}
+	m, err := pub.params.messageRepresentative(pub.algName, "verify",
+		message, context)
+	if err != nil {
+		return false, err
+	}
+
+	verifier, err := NewSignature(pub.params.mldsa)
+	if err != nil {
+		return false, err
+	}
+	defer verifier.Clean()
+	lenMLDSA := verifier.Details().MaxLengthSignature
+	if len(signature) <= lenMLDSA {
+		return false, nil
+	}
+	sigMLDSA, sigTrad := signature[:lenMLDSA], signature[lenMLDSA:]
+	isValid, err := verifier.VerifyWithCtxStr(m, sigMLDSA,
+		[]byte(pub.params.label), pub.mldsa)
+	if err != nil || !isValid {
+		return false, err
+	}
+
+	switch k := pub.trad.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, m, sigTrad), nil
+	case *ecdsa.PublicKey:
+		h := pub.params.tradHash.New()
+		h.Write(m)
+		return ecdsa.VerifyASN1(k, h.Sum(nil), sigTrad), nil
+	}
+	return false, nil
+}
+
+// CompositePrivateKey is a composite signature secret key, made of an ML-DSA
+// secret key and a traditional (Ed25519 or ECDSA) secret key. It implements
+// crypto.Signer.
+type CompositePrivateKey struct {
+	signer *Signature
+	trad   crypto.Signer
+	public *CompositePublicKey
+}
+
+// newCompositePrivateKey expands the ML-DSA seed of a composite secret key and
+// pairs it with the traditional secret key trad.
+func newCompositePrivateKey(algName string, params *compositeSigParams,
+	seed []byte, trad crypto.Signer,
+) (*CompositePrivateKey, error) {
+	signer, err := NewSignature(params.mldsa)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := signer.GenerateKeyPairFromSeed(seed)
+	if err != nil {
+		signer.Clean()
+		return nil, err
+	}
+	return &CompositePrivateKey{
+		signer: signer,
+		trad:   trad,
+		public: &CompositePublicKey{
+			algName: algName,
+			params:  params,
+			mldsa:   publicKey,
+			trad:    trad.Public(),
+		},
+	}, nil
+}
+
+// GenerateCompositeKey generates a fresh key pair for the composite algorithm
+// algName, see SupportedCompositeSigs.
+func GenerateCompositeKey(algName string) (*CompositePrivateKey, error) {
+	params, err := lookupCompositeSig(algName, "keypair")
+	if err != nil {
+		return nil, err
+	}
+	seed, err := NewSecretBuffer(compositeSeedLength)
+	if err != nil {
+		return nil, newError(algName, "keypair", err)
+	}
+	defer seed.Destroy()
+	if _, err := io.ReadFull(Rand, seed.Bytes()); err != nil {
+		return nil, newError(algName, "keypair", err)
+	}
+	var trad crypto.Signer
+	if params.curve == nil {
+		_, trad, err = ed25519.GenerateKey(Rand)
+	} else {
+		trad, err = ecdsa.GenerateKey(params.curve, Rand)
+	}
+	if err != nil {
+		return nil, newError(algName, "keypair", err)
+	}
+	return newCompositePrivateKey(algName, params, seed.Bytes(), trad)
+}
+
+// ParseCompositePrivateKey parses a composite secret key of the composite
+// algorithm algName, i.e. the 32-byte ML-DSA seed followed by the Ed25519
+// seed or the DER-encoded ECPrivateKey (RFC 5915).
+func ParseCompositePrivateKey(algName string, secretKey []byte) (
+	*CompositePrivateKey, error,
+) {
+	params, err := lookupCompositeSig(algName, "private key")
+	if err != nil {
+		return nil, err
+	}
+	if len(secretKey) < compositeSeedLength {
+		return nil, newError(algName, "private key", ErrInvalidSecretKeyLength)
+	}
+	seed, tradKey := secretKey[:compositeSeedLength],
+		secretKey[compositeSeedLength:]
+	var trad crypto.Signer
+	if params.curve == nil {
+		if len(tradKey) != ed25519.SeedSize {
+			return nil, newError(algName, "private key",
+				ErrInvalidSecretKeyLength)
+		}
+		trad = ed25519.NewKeyFromSeed(tradKey)
+	} else {
+		k, err := x509.ParseECPrivateKey(tradKey)
+		if err != nil || k.Curve != params.curve {
+			return nil, newError(algName, "private key", ErrInvalidSecretKey)
+		}
+		trad = k
+	}
+	return newCompositePrivateKey(algName, params, seed, trad)
+}
+
+// Bytes returns the encoding of the composite secret key, i.e. the 32-byte
+// ML-DSA seed followed by the Ed25519 seed or the DER-encoded ECPrivateKey
+// (RFC 5915). The caller should cleanse it with MemCleanse once done.
+func (priv *CompositePrivateKey) Bytes() ([]byte, error) {
+	var trad []byte
+	switch k := priv.trad.(type) {
+	case ed25519.PrivateKey:
+		trad = k.Seed()
+	case *ecdsa.PrivateKey:
+		var err error
+		if trad, err = x509.MarshalECPrivateKey(k); err != nil {
+			return nil, err
+		}
+	}
+	secretKey := append(append([]byte(nil), priv.signer.seed...), trad...)
+	MemCleanse(trad)
+	return secretKey, nil
+}
+
+// Algorithm returns the composite algorithm name.
+func (priv *CompositePrivateKey) Algorithm() string {
+	return priv.public.algName
+}
+
+// Public returns the corresponding *CompositePublicKey, as required by
+// crypto.Signer.
+func (priv *CompositePrivateKey) Public() crypto.PublicKey {
+	return priv.public
+}
+
+// Sign signs message and returns the composite signature, i.e. the ML-DSA
+// signature followed by the traditional signature, as required by
+// crypto.Signer. message must not be pre-hashed, and opts.HashFunc() must
+// return zero. opts may be nil, or a *SignerOpts carrying a context string.
+// The random argument is used by the traditional component; Rand is used if
+// it is nil.
+func (priv *CompositePrivateKey) Sign(random io.Reader, message []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	algName := priv.public.algName
+	params := priv.public.params
+	context, err := signerContext(algName, "sign", opts)
+	if err != nil {
+		return nil, err
+	}
+	m, err := params.messageRepresentative(algName, "sign", message, context)
+	if err != nil {
+		return nil, err
+	}
+	sigMLDSA, err := priv.signer.SignWithCtxStr(m, []byte(params.label))
+	if err != nil {
+		return nil, err
+	}
+	var sigTrad []byte
+	switch k := priv.trad.(type) {
+	case ed25519.PrivateKey:
+		sigTrad = ed25519.Sign(k, m)
+	case *ecdsa.PrivateKey:
+		if random == nil {
+			random = Rand
+		}
+		h := params.tradHash.New()
+		h.Write(m)
+		if sigTrad, err = ecdsa.SignASN1(random, k, h.Sum(nil)); err != nil {
+			return nil, newError(algName, "sign", err)
+		}
+	}
+	return append(sigMLDSA, sigTrad...), nil
+}
+
+// Verify verifies a composite signature against the public key of priv, see
+// CompositePublicKey.Verify.
+func (priv *CompositePrivateKey) Verify(message, signature []byte,
+	opts crypto.SignerOpts,
+) (bool, error) {
+	return priv.public.Verify(message, signature, opts)
+}
+
+// Clean zeroes-in the ML-DSA secret key and frees the underlying Signature.
+func (priv *CompositePrivateKey) Clean() {
+	priv.signer.Clean()
+}
+
+/**************** END Composite signatures ****************/
@@ -0,0 +1,145 @@
+package oqs
+
+import (
+	"errors"
+	"fmt"
+)
+
+/**************** Errors ****************/
+
+// Sentinel errors returned (wrapped in an *Error) by the oqs package. Use
+// errors.Is to test for them, e.g.
+// errors.Is(err, oqs.ErrInvalidPublicKeyLength).
+var (
+	// ErrAlgorithmNotSupported means that liboqs does not know the algorithm.
+	ErrAlgorithmNotSupported = errors.New("algorithm is not supported by OQS")
+	// ErrAlgorithmNotEnabled means that the algorithm is known to liboqs, but
+	// was disabled when liboqs was compiled.
+	ErrAlgorithmNotEnabled = errors.New("algorithm is not enabled by OQS")
+	// ErrAlgorithmIDOutOfRange means that a numerical algorithm ID exceeds the
+	// number of algorithms supported by liboqs.
+	ErrAlgorithmIDOutOfRange = errors.New("algorithm ID out of range")
+	// ErrNotInitialized means that the KeyEncapsulation or Signature was not
+	// initialized, or was already cleaned or returned to a pool.
+	ErrNotInitialized = errors.New("not initialized, make sure you run Init()")
+	// ErrInvalidPublicKey means that a public key is malformed.
+	ErrInvalidPublicKey = errors.New("invalid public key")
+	// ErrInvalidSecretKey means that a secret key is malformed.
+	ErrInvalidSecretKey = errors.New("invalid secret key")
+	// ErrInvalidCiphertext means that a ciphertext is malformed.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	// ErrInvalidPublicKeyLength means that a public key has the wrong length.
+	ErrInvalidPublicKeyLength = errors.New("incorrect public key length")
+	// ErrInvalidSecretKeyLength means that a secret key has the wrong length.
+	ErrInvalidSecretKeyLength = errors.New("incorrect secret key length")
+	// ErrInvalidCiphertextLength means that a ciphertext has the wrong length.
+	ErrInvalidCiphertextLength = errors.New("incorrect ciphertext length")
+	// ErrInvalidSignatureLength means that a signature is longer than the
+	// maximum signature length of the algorithm.
+	ErrInvalidSignatureLength = errors.New("incorrect signature size")
+	// ErrVerificationFailed means that a signature of a batch is invalid, see
+	// Signature.VerifyBatch.
+	ErrVerificationFailed = errors.New("signature verification failed")
+	// ErrPublicKeyUnavailable means that the public key can not be derived
+	// from the secret key.
+	ErrPublicKeyUnavailable = errors.New("public key is not derivable " +
+		"from the secret key")
+	// ErrKeyPairMismatch means that a public key does not match a secret key.
+	ErrKeyPairMismatch = errors.New("public key does not match the secret " +
+		"key")
+	// ErrNoSecretKey means that an operation requires a secret key, but none
+	// was specified in Init() nor generated by GenerateKeyPair().
+	ErrNoSecretKey = errors.New("no secret key, make sure you specify one " +
+		"in Init() or run GenerateKeyPair()")
+	// ErrContextNotSupported means that a non-empty context string was used
+	// with an algorithm that does not support context strings.
+	ErrContextNotSupported = errors.New("context string is not supported")
+	// ErrUnsupportedHash means that crypto.SignerOpts requested a pre-hash
+	// function that is not supported.
+	ErrUnsupportedHash = errors.New("unsupported hash function")
+	// ErrUnknownOID means that an algorithm has no OID, or that an OID does not
+	// correspond to any known algorithm.
+	ErrUnknownOID = errors.New("unknown algorithm OID")
+	// ErrUnsupportedKeyType means that a key of an unexpected Go type was
+	// passed to an encoding function.
+	ErrUnsupportedKeyType = errors.New("unsupported key type")
+	// ErrNoSeed means that a private key format requires the key generation
+	// seed, which is not available.
+	ErrNoSeed = errors.New("key generation seed not available")
+	// ErrDerandNotSupported means that an algorithm does not support
+	// deterministic key generation or encapsulation from a seed.
+	ErrDerandNotSupported = errors.New("deterministic operation is not " +
+		"supported")
+	// ErrInvalidSeedLength means that a seed has the wrong length.
+	ErrInvalidSeedLength = errors.New("incorrect seed length")
+	// ErrInvalidPEM means that the input has no PEM block of the expected
+	// type.
+	ErrInvalidPEM = errors.New("no PEM block of the expected type")
+	// ErrRandFailure means that the io.Reader configured by WithRand failed,
+	// hence the result of the operation was discarded.
+	ErrRandFailure = errors.New("random number generator failed")
+	// ErrNilCallback means that a nil callback was provided.
+	ErrNilCallback = errors.New("the RNG algorithm callback can not be nil")
+	// ErrNilSecretKeyStore means that a StatefulSignature was created without
+	// a secret key state store; see NewStatefulSignatureFile.
+	ErrNilSecretKeyStore = errors.New("the secret key state store can not " +
+		"be nil")
+	// ErrSecretKeyStore means that the updated state of a stateful secret key
+	// could not be persisted, hence the signature was withheld.
+	ErrSecretKeyStore = errors.New("secret key state could not be persisted")
+	// ErrSecretKeyStoreLocked means that a FileSecretKeyStore is already in
+	// use by another store, possibly in another process.
+	ErrSecretKeyStoreLocked = errors.New("secret key state file is locked")
+	// ErrSecretKeyExists means that generating a stateful key pair would
+	// overwrite an existing secret key state.
+	ErrSecretKeyExists = errors.New("secret key state already exists")
+	// ErrSignaturesExhausted means that a stateful secret key has no one-time
+	// keys left.
+	ErrSignaturesExhausted = errors.New("no signatures remaining")
+	// ErrSecretMemory means that the memory of a SecretBuffer could not be
+	// allocated.
+	ErrSecretMemory = errors.New("secret memory could not be allocated")
+	// ErrLiboqsFailure means that a liboqs function did not return
+	// OQS_SUCCESS; the raw status is available in Error.Status.
+	ErrLiboqsFailure = errors.New("liboqs operation failed")
+)
+
+// Error describes a failed operation of the oqs package. It wraps one of the
+// sentinel errors above, so it can be inspected with both errors.Is and
+// errors.As.
+type Error struct {
+	Alg    string // algorithm name, empty if not applicable
+	Op     string // operation, e.g. "keypair", "encaps", "sign"
+	Status int    // raw OQS_STATUS, 0 (OQS_SUCCESS) if liboqs was not invoked
+	Err    error  // underlying sentinel error
+}
+
+// Error returns the string representation of the error.
+func (e *Error) Error() string {
+	msg := e.Op + ": " + e.Err.Error()
+	if e.Alg != "" {
+		msg = `"` + e.Alg + `" ` + msg
+	}
+	if e.Status != 0 {
+		msg += fmt.Sprintf(" (OQS_STATUS %d)", e.Status)
+	}
+	return msg
+}
+
+// Unwrap returns the underlying sentinel error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError constructs an *Error that does not originate from a liboqs status.
+func newError(algName, op string, err error) error {
+	return &Error{Alg: algName, Op: op, Err: err}
+}
+
+// newStatusError constructs an *Error from a liboqs status other than
+// OQS_SUCCESS.
+func newStatusError(algName, op string, status int) error {
+	return &Error{Alg: algName, Op: op, Status: status, Err: ErrLiboqsFailure}
+}
+
+/**************** END Errors ****************/
@@ -0,0 +1,94 @@
+package oqs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+/**************** FileSecretKeyStore ****************/
+
+// FileSecretKeyStore is the default SecretKeyStore, which persists the state
+// of a stateful secret key to a file. Each Store writes the state to a
+// temporary file in the same directory, fsyncs it, atomically renames it over
+// the key file and fsyncs the directory, so that a crash leaves either the
+// previous or the new state on disk. An exclusive lock on the file path +
+// ".lock", held from NewFileSecretKeyStore until Close, prevents two
+// processes from signing with the same key.
+type FileSecretKeyStore struct {
+	path string
+	lock *os.File
+}
+
+// NewFileSecretKeyStore returns a store for the key file path, and acquires
+// its lock. It fails with ErrSecretKeyStoreLocked if the lock is held by
+// another store, possibly in another process.
+func NewFileSecretKeyStore(path string) (*FileSecretKeyStore, error) {
+	lock, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return &FileSecretKeyStore{path: path, lock: lock}, nil
+}
+
+// Path returns the path of the key file.
+func (fs *FileSecretKeyStore) Path() string {
+	return fs.path
+}
+
+// Load reads the last persisted secret key state, to be imported with
+// WithSecretKey. It returns an error satisfying errors.Is(err,
+// os.ErrNotExist) if no state was persisted yet.
+func (fs *FileSecretKeyStore) Load() ([]byte, error) {
+	return os.ReadFile(fs.path)
+}
+
+// Store durably replaces the persisted secret key state.
+func (fs *FileSecretKeyStore) Store(secretKey []byte) (err error) {
+	if fs.lock == nil {
+		return errors.New("secret key store is closed")
+	}
+	dir := filepath.Dir(fs.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(fs.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = tmp.Write(secretKey); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), fs.path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Close releases the lock. The store must not be used afterwards.
+func (fs *FileSecretKeyStore) Close() error {
+	if fs.lock == nil {
+		return nil
+	}
+	err := unlockFile(fs.lock)
+	if cerr := fs.lock.Close(); err == nil {
+		err = cerr
+	}
+	fs.lock = nil
+	return err
+}
+
+/**************** END FileSecretKeyStore ****************/
@@ -0,0 +1,23 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd || windows)
+
+package oqs
+
+import (
+	"errors"
+	"os"
+)
+
+// lockFile is not supported on this platform.
+func lockFile(*os.File) error {
+	return errors.ErrUnsupported
+}
+
+// unlockFile is not supported on this platform.
+func unlockFile(*os.File) error {
+	return errors.ErrUnsupported
+}
+
+// syncDir is not supported on this platform.
+func syncDir(string) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,33 @@
+//go:build windows
+
+package oqs
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockFile acquires an exclusive, non-blocking lock on f.
+func lockFile(f *os.File) error {
+	err := windows.LockFileEx(windows.Handle(f.Fd()),
+		windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY,
+		0, 1, 0, new(windows.Overlapped))
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return ErrSecretKeyStoreLocked
+	}
+	return err
+}
+
+// unlockFile releases the lock acquired by lockFile.
+func unlockFile(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0,
+		new(windows.Overlapped))
+}
+
+// syncDir is a no-op, since Windows persists renames together with the file
+// metadata and does not support syncing directories.
+func syncDir(string) error {
+	return nil
+}
@@ -0,0 +1,557 @@
+// Package hpke implements Hybrid Public Key Encryption (HPKE, RFC 9180) with
+// the post-quantum KEMs of the oqs package: ML-KEM-512, ML-KEM-768 and
+// ML-KEM-1024, and the X25519 + ML-KEM-768 hybrid X-Wing, using the
+// codepoints assigned by the HPKE post-quantum drafts. The Base and PSK modes
+// are supported, with the HKDF key derivation functions and the AES-GCM and
+// ChaCha20-Poly1305 AEADs.
+//
+// As in the HPKE post-quantum drafts, recipient secret keys are serialized
+// as the seeds their key pairs are derived from: the 64-byte seed d || z of
+// ML-KEM, and the 32-byte X-Wing secret key.
+package hpke // import "github.com/open-quantum-safe/liboqs-go/oqs/hpke"
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+/**************** Errors ****************/
+
+var (
+	// ErrUnsupportedSuite means that a KEM, KDF or AEAD identifier is not
+	// supported.
+	ErrUnsupportedSuite = errors.New("hpke: unsupported cipher suite")
+	// ErrInconsistentPSK means that exactly one of the PSK and the PSK ID is
+	// empty.
+	ErrInconsistentPSK = errors.New("hpke: inconsistent PSK inputs")
+	// ErrOpen means that a ciphertext could not be authenticated.
+	ErrOpen = errors.New("hpke: message authentication failed")
+	// ErrMessageLimit means that the sequence number of a context is
+	// exhausted.
+	ErrMessageLimit = errors.New("hpke: message limit reached")
+	// ErrExportOnly means that Seal or Open was called on a context of the
+	// export-only AEAD.
+	ErrExportOnly = errors.New("hpke: export-only AEAD")
+	// ErrExportLength means that the requested exported secret is too long.
+	ErrExportLength = errors.New("hpke: export length too large")
+)
+
+/**************** END Errors ****************/
+
+/**************** Algorithms ****************/
+
+// KEM is an HPKE KEM identifier.
+type KEM uint16
+
+// KEM identifiers.
+const (
+	KEMMLKEM512  KEM = 0x0040
+	KEMMLKEM768  KEM = 0x0041
+	KEMMLKEM1024 KEM = 0x0042
+	// KEMXWing is the X25519 + ML-KEM-768 hybrid KEM, i.e. X-Wing.
+	KEMXWing KEM = 0x647a
+)
+
+// KDF is an HPKE KDF identifier.
+type KDF uint16
+
+// KDF identifiers.
+const (
+	KDFHKDFSHA256 KDF = 0x0001
+	KDFHKDFSHA384 KDF = 0x0002
+	KDFHKDFSHA512 KDF = 0x0003
+)
+
+// AEAD is an HPKE AEAD identifier.
+type AEAD uint16
+
+// AEAD identifiers.
+const (
+	AEADAES128GCM        AEAD = 0x0001
+	AEADAES256GCM        AEAD = 0x0002
+	AEADChaCha20Poly1305 AEAD = 0x0003
+	// AEADExportOnly provides no encryption, only secret export.
+	AEADExportOnly AEAD = 0xffff
+)
+
+// HPKE modes.
+const (
+	modeBase byte = 0x00
+	modePSK  byte = 0x01
+)
+
+// kemNames maps the supported KEMs to oqs algorithm names.
+var kemNames = map[KEM]string{
+	KEMMLKEM512:  "ML-KEM-512",
+	KEMMLKEM768:  "ML-KEM-768",
+	KEMMLKEM1024: "ML-KEM-1024",
+	KEMXWing:     "X-Wing",
+}
+
+// kdfHashes maps the supported KDFs to their hash functions.
+var kdfHashes = map[KDF]func() hash.Hash{
+	KDFHKDFSHA256: sha256.New,
+	KDFHKDFSHA384: sha512.New384,
+	KDFHKDFSHA512: sha512.New,
+}
+
+// aeadKeyLengths maps the supported AEADs to their key lengths Nk.
+var aeadKeyLengths = map[AEAD]int{
+	AEADAES128GCM:        16,
+	AEADAES256GCM:        32,
+	AEADChaCha20Poly1305: chacha20poly1305.KeySize,
+	AEADExportOnly:       0,
+}
+
+// nonceLength is the nonce length Nn of all the supported AEADs.
+const nonceLength = 12
+
+// kemInstance is implemented by oqs.KeyEncapsulation and
+// oqs.HybridKeyEncapsulation.
+type kemInstance interface {
+	Details() oqs.KeyEncapsulationDetails
+	GenerateKeyPairFromSeed(seed []byte) ([]byte, error)
+	EncapSecret(publicKey []byte) (ciphertext, sharedSecret []byte, err error)
+	EncapSecretDeterministic(publicKey, seed []byte) (ciphertext,
+		sharedSecret []byte, err error)
+	DecapSecret(ciphertext []byte) ([]byte, error)
+	Clean()
+}
+
+// newKEM returns an instance of the KEM kemID.
+func newKEM(kemID KEM) (kemInstance, error) {
+	if kemID == KEMXWing {
+		return oqs.NewHybridKeyEncapsulation(kemNames[kemID])
+	}
+	return oqs.NewKeyEncapsulation(kemNames[kemID])
+}
+
+/**************** END Algorithms ****************/
+
+/**************** Suite ****************/
+
+// Suite is an HPKE cipher suite, i.e. a combination of KEM, KDF and AEAD.
+type Suite struct {
+	kemID  KEM
+	kdfID  KDF
+	aeadID AEAD
+}
+
+// NewSuite returns the cipher suite made of kemID, kdfID and aeadID, or
+// ErrUnsupportedSuite if any of them is not supported, or if the KEM is not
+// enabled in liboqs.
+func NewSuite(kemID KEM, kdfID KDF, aeadID AEAD) (Suite, error) {
+	name, okKEM := kemNames[kemID]
+	_, okKDF := kdfHashes[kdfID]
+	_, okAEAD := aeadKeyLengths[aeadID]
+	if !okKEM || !okKDF || !okAEAD {
+		return Suite{}, ErrUnsupportedSuite
+	}
+	if kemID == KEMXWing {
+		name = "ML-KEM-768"
+	}
+	if !oqs.IsKEMEnabled(name) {
+		return Suite{}, ErrUnsupportedSuite
+	}
+	return Suite{kemID: kemID, kdfID: kdfID, aeadID: aeadID}, nil
+}
+
+// String converts the suite to a string representation.
+func (s Suite) String() string {
+	return kemNames[s.kemID] + "/" + s.kdfID.String() + "/" + s.aeadID.String()
+}
+
+// String converts the KDF identifier to a string representation.
+func (kdfID KDF) String() string {
+	switch kdfID {
+	case KDFHKDFSHA256:
+		return "HKDF-SHA256"
+	case KDFHKDFSHA384:
+		return "HKDF-SHA384"
+	case KDFHKDFSHA512:
+		return "HKDF-SHA512"
+	}
+	return "unknown KDF"
+}
+
+// String converts the AEAD identifier to a string representation.
+func (aeadID AEAD) String() string {
+	switch aeadID {
+	case AEADAES128GCM:
+		return "AES-128-GCM"
+	case AEADAES256GCM:
+		return "AES-256-GCM"
+	case AEADChaCha20Poly1305:
+		return "ChaCha20Poly1305"
+	case AEADExportOnly:
+		return "Export-only"
+	}
+	return "unknown AEAD"
+}
+
+// suiteID returns the suite_id used for domain separation.
+func (s Suite) suiteID() []byte {
+	id := []byte("HPKE")
+	id = binary.BigEndian.AppendUint16(id, uint16(s.kemID))
+	id = binary.BigEndian.AppendUint16(id, uint16(s.kdfID))
+	return binary.BigEndian.AppendUint16(id, uint16(s.aeadID))
+}
+
+// labeledExtract implements LabeledExtract of RFC 9180.
+func (s Suite) labeledExtract(salt []byte, label string, ikm []byte) []byte {
+	labeledIKM := append([]byte("HPKE-v1"), s.suiteID()...)
+	labeledIKM = append(labeledIKM, label...)
+	labeledIKM = append(labeledIKM, ikm...)
+	return hkdf.Extract(kdfHashes[s.kdfID], labeledIKM, salt)
+}
+
+// labeledExpand implements LabeledExpand of RFC 9180.
+func (s Suite) labeledExpand(prk []byte, label string, info []byte,
+	length int,
+) ([]byte, error) {
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(labeledInfo, "HPKE-v1"...)
+	labeledInfo = append(labeledInfo, s.suiteID()...)
+	labeledInfo = append(labeledInfo, label...)
+	labeledInfo = append(labeledInfo, info...)
+	out := make([]byte, length)
+	if _, err := hkdf.Expand(kdfHashes[s.kdfID], prk,
+		labeledInfo).Read(out); err != nil {
+		return nil, ErrExportLength
+	}
+	return out, nil
+}
+
+// labeledDerive implements LabeledDerive of the HPKE post-quantum drafts
+// with SHAKE256, the one-stage KDF used by the KEM key derivation.
+func (s Suite) labeledDerive(ikm []byte, label string, context []byte,
+	length int,
+) []byte {
+	shake := sha3.NewShake256()
+	_, _ = shake.Write(ikm)
+	_, _ = shake.Write([]byte("HPKE-v1KEM"))
+	_, _ = shake.Write(binary.BigEndian.AppendUint16(nil, uint16(s.kemID)))
+	_, _ = shake.Write(binary.BigEndian.AppendUint16(nil, uint16(len(label))))
+	_, _ = shake.Write([]byte(label))
+	_, _ = shake.Write(binary.BigEndian.AppendUint16(nil, uint16(length)))
+	_, _ = shake.Write(context)
+	out := make([]byte, length)
+	_, _ = shake.Read(out)
+	return out
+}
+
+// GenerateKeyPair generates a recipient key pair for the KEM of the suite.
+// The secret key is the seed of the key pair.
+func (s Suite) GenerateKeyPair() (publicKey, secretKey []byte, err error) {
+	kem, err := newKEM(s.kemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer kem.Clean()
+	secretKey = oqs.RandomBytes(kem.Details().LengthKeypairSeed)
+	if publicKey, err = kem.GenerateKeyPairFromSeed(secretKey); err != nil {
+		oqs.MemCleanse(secretKey)
+		return nil, nil, err
+	}
+	return publicKey, secretKey, nil
+}
+
+// DeriveKeyPair deterministically derives a recipient key pair for the KEM
+// of the suite from the input keying material ikm, which must have at least
+// as much entropy as the secret key. The secret key is the seed of the key
+// pair.
+func (s Suite) DeriveKeyPair(ikm []byte) (publicKey, secretKey []byte,
+	err error,
+) {
+	kem, err := newKEM(s.kemID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer kem.Clean()
+	secretKey = s.labeledDerive(ikm, "DeriveKeyPair", nil,
+		kem.Details().LengthKeypairSeed)
+	if publicKey, err = kem.GenerateKeyPairFromSeed(secretKey); err != nil {
+		oqs.MemCleanse(secretKey)
+		return nil, nil, err
+	}
+	return publicKey, secretKey, nil
+}
+
+// keySchedule implements KeySchedule of RFC 9180.
+func (s Suite) keySchedule(mode byte, sharedSecret, info, psk,
+	pskID []byte,
+) (*context, error) {
+	if (len(psk) == 0) != (len(pskID) == 0) {
+		return nil, ErrInconsistentPSK
+	}
+	if mode == modeBase && len(psk) != 0 ||
+		mode == modePSK && len(psk) == 0 {
+		return nil, ErrInconsistentPSK
+	}
+	keyScheduleContext := []byte{mode}
+	keyScheduleContext = append(keyScheduleContext,
+		s.labeledExtract(nil, "psk_id_hash", pskID)...)
+	keyScheduleContext = append(keyScheduleContext,
+		s.labeledExtract(nil, "info_hash", info)...)
+	secret := s.labeledExtract(sharedSecret, "secret", psk)
+	defer oqs.MemCleanse(secret)
+
+	ctx := &context{suite: s}
+	var err error
+	if ctx.exporterSecret, err = s.labeledExpand(secret, "exp",
+		keyScheduleContext, kdfHashes[s.kdfID]().Size()); err != nil {
+		return nil, err
+	}
+	if s.aeadID == AEADExportOnly {
+		return ctx, nil
+	}
+	key, err := s.labeledExpand(secret, "key", keyScheduleContext,
+		aeadKeyLengths[s.aeadID])
+	if err != nil {
+		return nil, err
+	}
+	defer oqs.MemCleanse(key)
+	if ctx.baseNonce, err = s.labeledExpand(secret, "base_nonce",
+		keyScheduleContext, nonceLength); err != nil {
+		return nil, err
+	}
+	if s.aeadID == AEADChaCha20Poly1305 {
+		ctx.aead, err = chacha20poly1305.New(key)
+	} else {
+		var block cipher.Block
+		if block, err = aes.NewCipher(key); err == nil {
+			ctx.aead, err = cipher.NewGCM(block)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ctx, nil
+}
+
+// NewSender sets up a Base mode sender context that encrypts to the
+// recipient publicKey. The encapsulated key returned by Sender.Enc must be
+// transmitted to the recipient.
+func (s Suite) NewSender(publicKey, info []byte) (*Sender, error) {
+	return s.newSender(modeBase, publicKey, info, nil, nil, nil)
+}
+
+// NewSenderDeterministic is like NewSender, but derives the encapsulated key
+// from seed instead of the RNG: the 32-byte message m of ML-KEM, or the
+// 64-byte concatenation of m and of the ephemeral X25519 secret key of
+// X-Wing. It is meant for known-answer tests; the seed must be secret and
+// never reused.
+func (s Suite) NewSenderDeterministic(publicKey, info, seed []byte) (*Sender,
+	error,
+) {
+	if seed == nil {
+		seed = []byte{}
+	}
+	return s.newSender(modeBase, publicKey, info, nil, nil, seed)
+}
+
+// NewSenderPSK sets up a PSK mode sender context, which additionally
+// authenticates the sender as a holder of the pre-shared key psk, identified
+// by pskID.
+func (s Suite) NewSenderPSK(publicKey, info, psk, pskID []byte) (*Sender,
+	error,
+) {
+	return s.newSender(modePSK, publicKey, info, psk, pskID, nil)
+}
+
+// newSender implements SetupBaseS and SetupPSKS of RFC 9180, deriving the
+// encapsulation randomness from seed unless it is nil.
+func (s Suite) newSender(mode byte, publicKey, info, psk, pskID,
+	seed []byte,
+) (*Sender, error) {
+	kem, err := newKEM(s.kemID)
+	if err != nil {
+		return nil, err
+	}
+	defer kem.Clean()
+	var enc, sharedSecret []byte
+	if seed == nil {
+		enc, sharedSecret, err = kem.EncapSecret(publicKey)
+	} else {
+		enc, sharedSecret, err = kem.EncapSecretDeterministic(publicKey, seed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer oqs.MemCleanse(sharedSecret)
+	ctx, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Sender{context: ctx, enc: enc}, nil
+}
+
+// NewReceiver sets up a Base mode recipient context from the recipient
+// secretKey, i.e. the seed of its key pair, and the encapsulated key enc
+// produced by the sender.
+func (s Suite) NewReceiver(secretKey, enc, info []byte) (*Receiver, error) {
+	return s.newReceiver(modeBase, secretKey, enc, info, nil, nil)
+}
+
+// NewReceiverPSK sets up a PSK mode recipient context.
+func (s Suite) NewReceiverPSK(secretKey, enc, info, psk, pskID []byte) (
+	*Receiver, error,
+) {
+	return s.newReceiver(modePSK, secretKey, enc, info, psk, pskID)
+}
+
+// newReceiver implements SetupBaseR and SetupPSKR of RFC 9180.
+func (s Suite) newReceiver(mode byte, secretKey, enc, info, psk,
+	pskID []byte,
+) (*Receiver, error) {
+	kem, err := newKEM(s.kemID)
+	if err != nil {
+		return nil, err
+	}
+	defer kem.Clean()
+	if _, err = kem.GenerateKeyPairFromSeed(secretKey); err != nil {
+		return nil, err
+	}
+	sharedSecret, err := kem.DecapSecret(enc)
+	if err != nil {
+		return nil, err
+	}
+	defer oqs.MemCleanse(sharedSecret)
+	ctx, err := s.keySchedule(mode, sharedSecret, info, psk, pskID)
+	if err != nil {
+		return nil, err
+	}
+	return &Receiver{context: ctx}, nil
+}
+
+// Seal encrypts plaintext to the recipient publicKey in a single shot, and
+// returns the encapsulated key and the ciphertext.
+func (s Suite) Seal(publicKey, info, aad, plaintext []byte) (enc,
+	ciphertext []byte, err error,
+) {
+	sender, err := s.NewSender(publicKey, info)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ciphertext, err = sender.Seal(aad, plaintext); err != nil {
+		return nil, nil, err
+	}
+	return sender.Enc(), ciphertext, nil
+}
+
+// Open decrypts a ciphertext produced by Seal in a single shot.
+func (s Suite) Open(secretKey, enc, info, aad, ciphertext []byte) ([]byte,
+	error,
+) {
+	receiver, err := s.NewReceiver(secretKey, enc, info)
+	if err != nil {
+		return nil, err
+	}
+	return receiver.Open(aad, ciphertext)
+}
+
+/**************** END Suite ****************/
+
+/**************** Contexts ****************/
+
+// context is the encryption context shared by senders and receivers.
+type context struct {
+	suite          Suite
+	aead           cipher.AEAD
+	baseNonce      []byte
+	seq            uint64
+	exporterSecret []byte
+}
+
+// nextNonce computes the nonce of the current message and increments the
+// sequence number.
+func (ctx *context) nextNonce() ([]byte, error) {
+	if ctx.aead == nil {
+		return nil, ErrExportOnly
+	}
+	if ctx.seq == math.MaxUint64 {
+		return nil, ErrMessageLimit
+	}
+	nonce := append([]byte(nil), ctx.baseNonce...)
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], ctx.seq)
+	for i := range seq {
+		nonce[nonceLength-8+i] ^= seq[i]
+	}
+	ctx.seq++
+	return nonce, nil
+}
+
+// Export derives a secret of the given length from the context, bound to
+// exporterContext.
+func (ctx *context) Export(exporterContext []byte, length int) ([]byte,
+	error,
+) {
+	if length < 0 || length > 255*len(ctx.exporterSecret) {
+		return nil, ErrExportLength
+	}
+	return ctx.suite.labeledExpand(ctx.exporterSecret, "sec",
+		exporterContext, length)
+}
+
+// Sender is an HPKE sender context. A Sender encrypts a sequence of
+// messages, and is not safe for concurrent use.
+type Sender struct {
+	*context
+	enc []byte
+}
+
+// Enc returns the encapsulated key to be transmitted to the recipient.
+func (sender *Sender) Enc() []byte {
+	return append([]byte(nil), sender.enc...)
+}
+
+// Seal encrypts and authenticates plaintext and authenticates aad, and
+// returns the ciphertext. Messages must be opened in the order they were
+// sealed.
+func (sender *Sender) Seal(aad, plaintext []byte) ([]byte, error) {
+	nonce, err := sender.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	return sender.aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// Receiver is an HPKE recipient context. A Receiver decrypts a sequence of
+// messages, and is not safe for concurrent use.
+type Receiver struct {
+	*context
+}
+
+// Open decrypts ciphertext and authenticates it together with aad. It
+// returns ErrOpen if authentication fails, in which case the sequence number
+// is not incremented.
+func (receiver *Receiver) Open(aad, ciphertext []byte) ([]byte, error) {
+	if receiver.aead == nil {
+		return nil, ErrExportOnly
+	}
+	if receiver.seq == math.MaxUint64 {
+		return nil, ErrMessageLimit
+	}
+	nonce, _ := receiver.nextNonce()
+	plaintext, err := receiver.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		receiver.seq--
+		return nil, ErrOpen
+	}
+	return plaintext, nil
+}
+
+/**************** END Contexts ****************/
@@ -0,0 +1,536 @@
+package oqs
+
+import (
+	"crypto/ecdh"
+	"fmt"
+	"io"
+	"runtime"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/**************** Hybrid KEMs ****************/
+
+// HybridCombiner selects how the shared secrets of the two components of a
+// HybridKeyEncapsulation are combined.
+type HybridCombiner int
+
+const (
+	// CombinerConcat concatenates the two shared secrets, as done by the
+	// hybrid key exchange in TLS 1.3 (draft-ietf-tls-ecdhe-mlkem).
+	CombinerConcat HybridCombiner = iota
+	// CombinerXWing hashes the two shared secrets together with the X25519
+	// ciphertext and public key, as done by X-Wing
+	// (draft-connolly-cfrg-xwing-kem-05). The secret key is then the 32-byte
+	// seed from which both component key pairs are expanded.
+	CombinerXWing
+)
+
+// xwingSeedLength is the length of an X-Wing secret key, i.e. of the seed
+// expanded into the component key pairs.
+const xwingSeedLength = 32
+
+// xwingLabel is the X-Wing domain separator, i.e. the ASCII string `\.//^\`.
+var xwingLabel = []byte{0x5c, 0x2e, 0x2f, 0x2f, 0x5e, 0x5c}
+
+// hybridKEMParams defines a named hybrid KEM.
+type hybridKEMParams struct {
+	curve    ecdh.Curve
+	kemName  string
+	combiner HybridCombiner
+}
+
+// hybridKEMs lists the named hybrid KEMs, following the IETF drafts.
+var hybridKEMs = map[string]hybridKEMParams{
+	"X25519MLKEM768":     {ecdh.X25519(), "ML-KEM-768", CombinerConcat},
+	"SecP256r1MLKEM768":  {ecdh.P256(), "ML-KEM-768", CombinerConcat},
+	"SecP384r1MLKEM1024": {ecdh.P384(), "ML-KEM-1024", CombinerConcat},
+	"X-Wing":             {ecdh.X25519(), "ML-KEM-768", CombinerXWing},
+}
+
+// SupportedHybridKEMs returns the list of named hybrid KEMs accepted by
+// NewHybridKeyEncapsulation.
+func SupportedHybridKEMs() []string {
+	return []string{
+		"X25519MLKEM768", "SecP256r1MLKEM768", "SecP384r1MLKEM1024", "X-Wing",
+	}
+}
+
+// ecdhLengths returns the public key, secret key and shared secret lengths of
+// an elliptic curve.
+func ecdhLengths(curve ecdh.Curve) (publicKey, secretKey, sharedSecret int,
+	ok bool,
+) {
+	switch curve {
+	case ecdh.X25519():
+		return 32, 32, 32, true
+	case ecdh.P256():
+		return 65, 32, 32, true
+	case ecdh.P384():
+		return 97, 48, 48, true
+	case ecdh.P521():
+		return 133, 66, 66, true
+	}
+	return 0, 0, 0, false
+}
+
+// HybridKeyEncapsulation combines an elliptic curve Diffie-Hellman key
+// exchange with a liboqs KEM, and remains secure as long as either component
+// is. Public keys, ciphertexts and secret keys are the concatenations of the
+// corresponding components; as in the IETF drafts, the post-quantum component
+// comes first for X25519, and last for the NIST curves. The same holds for the
+// seeds of GenerateKeyPairFromSeed and EncapSecretDeterministic. With
+// CombinerXWing, the secret key is instead a 32-byte seed, as in X-Wing.
+type HybridKeyEncapsulation struct {
+	kem        KeyEncapsulation
+	curve      ecdh.Curve
+	ecdhKey    *ecdh.PrivateKey
+	secretBuf  *SecretBuffer // holds seed
+	seed       []byte        // X-Wing secret key
+	combiner   HybridCombiner
+	kemFirst   bool
+	lenECDHPK  int
+	lenECDHSK  int
+	algDetails KeyEncapsulationDetails
+}
+
+// String converts the hybrid KEM algorithm name to a string representation.
+func (h *HybridKeyEncapsulation) String() string {
+	return fmt.Sprintf("Hybrid key encapsulation mechanism: %s",
+		h.algDetails.Name)
+}
+
+// NewHybridKeyEncapsulation allocates and initializes a named hybrid KEM, see
+// SupportedHybridKEMs, configured by opts (e.g. WithSecretKey). The caller
+// should invoke HybridKeyEncapsulation.Clean once done.
+func NewHybridKeyEncapsulation(algName string, opts ...Option) (
+	*HybridKeyEncapsulation, error,
+) {
+	params, ok := hybridKEMs[algName]
+	if !ok {
+		return nil, newError(algName, "init", ErrAlgorithmNotSupported)
+	}
+	h, err := NewCustomHybridKeyEncapsulation(params.curve, params.kemName,
+		params.combiner, opts...)
+	if err != nil {
+		return nil, err
+	}
+	h.algDetails.Name = algName
+	return h, nil
+}
+
+// NewCustomHybridKeyEncapsulation allocates and initializes a hybrid KEM made
+// of the elliptic curve curve (X25519, P-256, P-384 or P-521) and the enabled
+// liboqs KEM kemName, with the shared secrets combined by combiner. The
+// CombinerXWing combiner requires X25519. The caller should invoke
+// HybridKeyEncapsulation.Clean once done; a finalizer frees the underlying
+// liboqs object and cleanses the secret key if the caller forgets to.
+func NewCustomHybridKeyEncapsulation(curve ecdh.Curve, kemName string,
+	combiner HybridCombiner, opts ...Option,
+) (*HybridKeyEncapsulation, error) {
+	name := fmt.Sprintf("%v+%s", curve, kemName)
+	lenPK, lenSK, lenSS, ok := ecdhLengths(curve)
+	if !ok || (combiner == CombinerXWing && curve != ecdh.X25519()) ||
+		(combiner != CombinerConcat && combiner != CombinerXWing) {
+		return nil, newError(name, "init", ErrAlgorithmNotSupported)
+	}
+	h := &HybridKeyEncapsulation{
+		curve:     curve,
+		combiner:  combiner,
+		kemFirst:  curve == ecdh.X25519(),
+		lenECDHPK: lenPK,
+		lenECDHSK: lenSK,
+	}
+	if err := h.kem.Init(kemName, nil); err != nil {
+		return nil, err
+	}
+	kemDetails := h.kem.Details()
+	h.algDetails = KeyEncapsulationDetails{
+		Name:               name,
+		Version:            kemDetails.Version,
+		ClaimedNISTLevel:   kemDetails.ClaimedNISTLevel,
+		IsINDCCA:           kemDetails.IsINDCCA,
+		LengthPublicKey:    kemDetails.LengthPublicKey + lenPK,
+		LengthSecretKey:    kemDetails.LengthSecretKey + lenSK,
+		LengthCiphertext:   kemDetails.LengthCiphertext + lenPK,
+		LengthSharedSecret: kemDetails.LengthSharedSecret + lenSS,
+	}
+	if kemDetails.LengthKeypairSeed != 0 {
+		h.algDetails.LengthKeypairSeed = kemDetails.LengthKeypairSeed + lenSK
+	}
+	if kemDetails.LengthEncapsSeed != 0 {
+		h.algDetails.LengthEncapsSeed = kemDetails.LengthEncapsSeed + lenSK
+	}
+	if combiner == CombinerXWing {
+		// The secret key is a seed, expanded into the KEM seed and the X25519
+		// secret key.
+		if kemDetails.LengthKeypairSeed == 0 {
+			h.kem.Clean()
+			return nil, newError(name, "init", ErrDerandNotSupported)
+		}
+		h.algDetails.LengthSecretKey = xwingSeedLength
+		h.algDetails.LengthKeypairSeed = xwingSeedLength
+		h.algDetails.LengthSharedSecret = 32
+	}
+	o := newOptions(opts)
+	h.kem.rand = o.rand
+	h.kem.strict = o.strict
+	if o.secretKey != nil {
+		if err := h.importSecretKey(o.secretKey); err != nil {
+			h.Clean()
+			return nil, err
+		}
+	}
+	runtime.SetFinalizer(h, (*HybridKeyEncapsulation).Clean)
+	return h, nil
+}
+
+// Details returns the hybrid KEM algorithm details. As the ECDH secret key
+// lives on the Go heap, SecretKeyLocked only covers the KEM secret key and the
+// X-Wing seed.
+func (h *HybridKeyEncapsulation) Details() KeyEncapsulationDetails {
+	details := h.algDetails
+	details.SecretKeyLocked = h.kem.secretBuf.Locked() &&
+		(h.combiner != CombinerXWing || h.secretBuf.Locked())
+	return details
+}
+
+// join concatenates the KEM and ECDH components in the order of h.
+func (h *HybridKeyEncapsulation) join(kemPart, ecdhPart []byte) []byte {
+	out := make([]byte, 0, len(kemPart)+len(ecdhPart))
+	if h.kemFirst {
+		return append(append(out, kemPart...), ecdhPart...)
+	}
+	return append(append(out, ecdhPart...), kemPart...)
+}
+
+// split is the inverse of join, where lenECDH is the length of the ECDH
+// component. The input length must have been validated by the caller.
+func (h *HybridKeyEncapsulation) split(in []byte, lenECDH int) (kemPart,
+	ecdhPart []byte,
+) {
+	if h.kemFirst {
+		return in[:len(in)-lenECDH], in[len(in)-lenECDH:]
+	}
+	return in[lenECDH:], in[:lenECDH]
+}
+
+// sharedECDH returns the ECDH shared secret of priv and peer. Unlike the TLS
+// hybrids, X-Wing does not reject the low-order X25519 points, whose all-zero
+// shared secret it hashes as any other; crypto/ecdh only fails on these.
+func (h *HybridKeyEncapsulation) sharedECDH(priv *ecdh.PrivateKey,
+	peer *ecdh.PublicKey,
+) ([]byte, error) {
+	ss, err := priv.ECDH(peer)
+	if err != nil && h.combiner == CombinerXWing {
+		return make([]byte, h.lenECDHPK), nil
+	}
+	return ss, err
+}
+
+// combine derives the hybrid shared secret from the component shared secrets,
+// the ECDH ciphertext (ephemeral public key) and the recipient ECDH public
+// key.
+func (h *HybridKeyEncapsulation) combine(ssKEM, ssECDH, ctECDH,
+	pkECDH []byte,
+) []byte {
+	if h.combiner == CombinerXWing {
+		hash := sha3.New256()
+		hash.Write(ssKEM)
+		hash.Write(ssECDH)
+		hash.Write(ctECDH)
+		hash.Write(pkECDH)
+		hash.Write(xwingLabel)
+		MemCleanse(ssKEM)
+		MemCleanse(ssECDH)
+		return hash.Sum(nil)
+	}
+	ss := h.join(ssKEM, ssECDH)
+	MemCleanse(ssKEM)
+	MemCleanse(ssECDH)
+	return ss
+}
+
+// importSecretKey splits a hybrid secret key into its components, or expands
+// an X-Wing seed.
+func (h *HybridKeyEncapsulation) importSecretKey(secretKey []byte) error {
+	if len(secretKey) != h.algDetails.LengthSecretKey {
+		return newError(h.algDetails.Name, "init", ErrInvalidSecretKeyLength)
+	}
+	if h.combiner == CombinerXWing {
+		_, err := h.expandSeed(secretKey, "init")
+		return err
+	}
+	skKEM, skECDH := h.split(secretKey, h.lenECDHSK)
+	ecdhKey, err := h.curve.NewPrivateKey(skECDH)
+	if err != nil {
+		return newError(h.algDetails.Name, "init", ErrInvalidSecretKey)
+	}
+	h.kem.secretBuf, h.kem.secretKey, h.kem.seed, err = replaceSecretKey(
+		h.kem.secretBuf, skKEM, len(skKEM), nil)
+	if err != nil {
+		return newError(h.algDetails.Name, "init", err)
+	}
+	h.ecdhKey = ecdhKey
+	return nil
+}
+
+// reader returns the source of the randomness drawn by the h receiver itself,
+// i.e. the reader set by WithRand, serialized with the other operations that
+// use such a reader, or Rand.
+func (h *HybridKeyEncapsulation) reader() io.Reader {
+	if h.kem.rand != nil {
+		return serializedReader{h.kem.rand}
+	}
+	return Rand
+}
+
+// expandSeed expands an X-Wing seed into the KEM seed and the X25519 secret
+// key with SHAKE256, generates both key pairs, keeps a copy of the seed as
+// the secret key and returns the hybrid public key.
+func (h *HybridKeyEncapsulation) expandSeed(seed []byte, op string) ([]byte,
+	error,
+) {
+	lenSeedKEM := h.kem.algDetails.LengthKeypairSeed
+	expanded, err := NewSecretBuffer(lenSeedKEM + h.lenECDHSK)
+	if err != nil {
+		return nil, newError(h.algDetails.Name, op, err)
+	}
+	defer expanded.Destroy()
+	shake := sha3.NewShake256()
+	shake.Write(seed)
+	shake.Read(expanded.Bytes())
+	shake.Reset()
+
+	pkKEM, err := h.kem.GenerateKeyPairFromSeed(expanded.Bytes()[:lenSeedKEM])
+	if err != nil {
+		return nil, err
+	}
+	ecdhKey, err := h.curve.NewPrivateKey(expanded.Bytes()[lenSeedKEM:])
+	if err != nil {
+		return nil, newError(h.algDetails.Name, op, ErrInvalidSecretKey)
+	}
+	h.secretBuf, _, h.seed, err = replaceSecretKey(h.secretBuf, nil, 0, seed)
+	if err != nil {
+		return nil, newError(h.algDetails.Name, op, err)
+	}
+	h.ecdhKey = ecdhKey
+	return h.join(pkKEM, ecdhKey.PublicKey().Bytes()), nil
+}
+
+// GenerateKeyPair generates a pair of secret key/public key and returns the
+// public key. The secret key is stored inside the h receiver, and can be
+// exported with HybridKeyEncapsulation.ExportSecretKey.
+func (h *HybridKeyEncapsulation) GenerateKeyPair() ([]byte, error) {
+	if h.combiner == CombinerXWing {
+		seed, err := NewSecretBuffer(xwingSeedLength)
+		if err != nil {
+			return nil, newError(h.algDetails.Name, "keypair", err)
+		}
+		defer seed.Destroy()
+		if _, err := io.ReadFull(h.reader(), seed.Bytes()); err != nil {
+			return nil, newError(h.algDetails.Name, "keypair",
+				fmt.Errorf("%w: %w", ErrRandFailure, err))
+		}
+		return h.expandSeed(seed.Bytes(), "keypair")
+	}
+	pkKEM, err := h.kem.GenerateKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	ecdhKey, err := h.curve.GenerateKey(h.reader())
+	if err != nil {
+		return nil, newError(h.algDetails.Name, "keypair", err)
+	}
+	h.ecdhKey = ecdhKey
+	return h.join(pkKEM, ecdhKey.PublicKey().Bytes()), nil
+}
+
+// GenerateKeyPairFromSeed deterministically generates a pair of secret
+// key/public key from a seed of Details().LengthKeypairSeed bytes and returns
+// the public key. The seed is the X-Wing secret key itself, or otherwise the
+// concatenation of the KEM seed and of the ECDH secret key.
+func (h *HybridKeyEncapsulation) GenerateKeyPairFromSeed(seed []byte) (
+	[]byte, error,
+) {
+	if h.algDetails.LengthKeypairSeed == 0 {
+		return nil, newError(h.algDetails.Name, "keypair",
+			ErrDerandNotSupported)
+	}
+	if len(seed) != h.algDetails.LengthKeypairSeed {
+		return nil, newError(h.algDetails.Name, "keypair",
+			ErrInvalidSeedLength)
+	}
+	if h.combiner == CombinerXWing {
+		return h.expandSeed(seed, "keypair")
+	}
+	seedKEM, seedECDH := h.split(seed, h.lenECDHSK)
+	ecdhKey, err := h.curve.NewPrivateKey(seedECDH)
+	if err != nil {
+		return nil, newError(h.algDetails.Name, "keypair", ErrInvalidSecretKey)
+	}
+	pkKEM, err := h.kem.GenerateKeyPairFromSeed(seedKEM)
+	if err != nil {
+		return nil, err
+	}
+	h.ecdhKey = ecdhKey
+	return h.join(pkKEM, ecdhKey.PublicKey().Bytes()), nil
+}
+
+// ExportSecretKey exports a copy of the hybrid secret key, i.e. the X-Wing
+// seed, or otherwise the concatenation of the KEM and ECDH secret keys. The
+// caller should cleanse it with MemCleanse once done.
+func (h *HybridKeyEncapsulation) ExportSecretKey() []byte {
+	if h.ecdhKey == nil {
+		return nil
+	}
+	secretKey := make([]byte, h.algDetails.LengthSecretKey)
+	if _, err := h.ExportSecretKeyTo(secretKey); err != nil {
+		return nil
+	}
+	return secretKey
+}
+
+// ExportSecretKeyTo copies the hybrid secret key into dst without an
+// intermediate copy on the Go heap, see KeyEncapsulation.ExportSecretKeyTo,
+// and returns the number of bytes copied.
+func (h *HybridKeyEncapsulation) ExportSecretKeyTo(dst []byte) (int, error) {
+	if h.kem.kem == nil {
+		return 0, newError("", "export secret key", ErrNotInitialized)
+	}
+	if h.ecdhKey == nil {
+		return 0, newError(h.algDetails.Name, "export secret key",
+			ErrNoSecretKey)
+	}
+	if len(dst) < h.algDetails.LengthSecretKey {
+		return 0, newError(h.algDetails.Name, "export secret key",
+			io.ErrShortBuffer)
+	}
+	if h.combiner == CombinerXWing {
+		return copy(dst, h.seed), nil
+	}
+	skKEM, skECDH := h.split(dst[:h.algDetails.LengthSecretKey], h.lenECDHSK)
+	if _, err := h.kem.ExportSecretKeyTo(skKEM); err != nil {
+		return 0, err
+	}
+	ecdhBytes := h.ecdhKey.Bytes()
+	copy(skECDH, ecdhBytes)
+	MemCleanse(ecdhBytes)
+	return h.algDetails.LengthSecretKey, nil
+}
+
+// EncapSecret encapsulates a secret using a hybrid public key and returns the
+// corresponding hybrid ciphertext and shared secret.
+func (h *HybridKeyEncapsulation) EncapSecret(publicKey []byte) (ciphertext,
+	sharedSecret []byte, err error,
+) {
+	return h.encapSecret(publicKey, nil)
+}
+
+// EncapSecretDeterministic is like EncapSecret, but derives the ciphertext
+// and the shared secret from a seed of Details().LengthEncapsSeed bytes, i.e.
+// the concatenation of the KEM encapsulation seed and of the ephemeral ECDH
+// secret key, instead of the RNG. It is meant for known-answer tests; the
+// seed must be secret and never reused.
+func (h *HybridKeyEncapsulation) EncapSecretDeterministic(publicKey,
+	seed []byte,
+) (ciphertext, sharedSecret []byte, err error) {
+	if h.algDetails.LengthEncapsSeed == 0 {
+		return nil, nil, newError(h.algDetails.Name, "encaps",
+			ErrDerandNotSupported)
+	}
+	if len(seed) != h.algDetails.LengthEncapsSeed {
+		return nil, nil, newError(h.algDetails.Name, "encaps",
+			ErrInvalidSeedLength)
+	}
+	return h.encapSecret(publicKey, seed)
+}
+
+// encapSecret encapsulates a secret using a hybrid public key, deriving the
+// randomness from seed unless it is nil.
+func (h *HybridKeyEncapsulation) encapSecret(publicKey, seed []byte) (
+	ciphertext, sharedSecret []byte, err error,
+) {
+	if len(publicKey) != h.algDetails.LengthPublicKey {
+		return nil, nil, newError(h.algDetails.Name, "encaps",
+			ErrInvalidPublicKeyLength)
+	}
+	pkKEM, pkECDH := h.split(publicKey, h.lenECDHPK)
+	peer, err := h.curve.NewPublicKey(pkECDH)
+	if err != nil {
+		return nil, nil, newError(h.algDetails.Name, "encaps",
+			ErrInvalidPublicKey)
+	}
+	var ephemeral *ecdh.PrivateKey
+	var seedKEM, seedECDH []byte
+	if seed == nil {
+		ephemeral, err = h.curve.GenerateKey(h.reader())
+	} else {
+		seedKEM, seedECDH = h.split(seed, h.lenECDHSK)
+		ephemeral, err = h.curve.NewPrivateKey(seedECDH)
+	}
+	if err != nil {
+		return nil, nil, newError(h.algDetails.Name, "encaps", err)
+	}
+	ssECDH, err := h.sharedECDH(ephemeral, peer)
+	if err != nil {
+		return nil, nil, newError(h.algDetails.Name, "encaps",
+			ErrInvalidPublicKey)
+	}
+	var ctKEM, ssKEM []byte
+	if seed == nil {
+		ctKEM, ssKEM, err = h.kem.EncapSecret(pkKEM)
+	} else {
+		ctKEM, ssKEM, err = h.kem.EncapSecretDeterministic(pkKEM, seedKEM)
+	}
+	if err != nil {
+		MemCleanse(ssECDH)
+		return nil, nil, err
+	}
+	ctECDH := ephemeral.PublicKey().Bytes()
+	return h.join(ctKEM, ctECDH), h.combine(ssKEM, ssECDH, ctECDH, pkECDH),
+		nil
+}
+
+// DecapSecret decapsulates a hybrid ciphertext and returns the corresponding
+// hybrid shared secret.
+func (h *HybridKeyEncapsulation) DecapSecret(ciphertext []byte) ([]byte,
+	error,
+) {
+	if len(ciphertext) != h.algDetails.LengthCiphertext {
+		return nil, newError(h.algDetails.Name, "decaps",
+			ErrInvalidCiphertextLength)
+	}
+	if h.ecdhKey == nil {
+		return nil, newError(h.algDetails.Name, "decaps", ErrNoSecretKey)
+	}
+	ctKEM, ctECDH := h.split(ciphertext, h.lenECDHPK)
+	peer, err := h.curve.NewPublicKey(ctECDH)
+	if err != nil {
+		return nil, newError(h.algDetails.Name, "decaps",
+			ErrInvalidCiphertext)
+	}
+	ssECDH, err := h.sharedECDH(h.ecdhKey, peer)
+	if err != nil {
+		return nil, newError(h.algDetails.Name, "decaps",
+			ErrInvalidCiphertext)
+	}
+	ssKEM, err := h.kem.DecapSecret(ctKEM)
+	if err != nil {
+		return nil, err
+	}
+	return h.combine(ssKEM, ssECDH, ctECDH, h.ecdhKey.PublicKey().Bytes()),
+		nil
+}
+
+// Clean zeroes-in the stored KEM secret key and X-Wing seed, drops the ECDH
+// secret key and resets the h receiver. Clean is idempotent.
+func (h *HybridKeyEncapsulation) Clean() {
+	runtime.SetFinalizer(h, nil)
+	h.kem.Clean()
+	h.secretBuf.Destroy()
+	h.secretBuf, h.seed = nil, nil
+	h.ecdhKey = nil
+}
+
+/**************** END Hybrid KEMs ****************/
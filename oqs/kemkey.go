@@ -0,0 +1,155 @@
+package oqs
+
+import (
+	"crypto"
+	"crypto/subtle"
+)
+
+/**************** KEM keys ****************/
+
+// KEMPublicKey is a KEM public key tagged with its algorithm name. It
+// implements crypto.PublicKey.
+type KEMPublicKey struct {
+	algName   string
+	publicKey []byte
+}
+
+// NewKEMPublicKey wraps publicKey for the KEM algorithm algName. The public
+// key is copied and its length is validated.
+func NewKEMPublicKey(algName string, publicKey []byte) (*KEMPublicKey, error) {
+	kem, err := NewKeyEncapsulation(algName)
+	if err != nil {
+		return nil, err
+	}
+	defer kem.Clean()
+	if len(publicKey) != kem.Details().LengthPublicKey {
+		return nil, newError(algName, "public key", ErrInvalidPublicKeyLength)
+	}
+	return &KEMPublicKey{
+		algName:   algName,
+		publicKey: append([]byte(nil), publicKey...),
+	}, nil
+}
+
+// Algorithm returns the KEM algorithm name.
+func (pub *KEMPublicKey) Algorithm() string {
+	return pub.algName
+}
+
+// Bytes returns a copy of the raw public key.
+func (pub *KEMPublicKey) Bytes() []byte {
+	return append([]byte(nil), pub.publicKey...)
+}
+
+// Equal reports whether pub and x have the same algorithm and value.
+func (pub *KEMPublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*KEMPublicKey)
+	if !ok {
+		return false
+	}
+	return pub.algName == xx.algName &&
+		subtle.ConstantTimeCompare(pub.publicKey, xx.publicKey) == 1
+}
+
+// Encapsulate encapsulates a fresh secret to pub, and returns the ciphertext
+// to send to the owner of the private key, together with the shared secret.
+func (pub *KEMPublicKey) Encapsulate() (ciphertext, sharedSecret []byte,
+	err error,
+) {
+	kem, err := NewKeyEncapsulation(pub.algName)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer kem.Clean()
+	return kem.EncapSecret(pub.publicKey)
+}
+
+// KEMPrivateKey is a KEM secret key together with its public key. Unlike a
+// KeyEncapsulation, it can only decapsulate, and it carries its algorithm
+// name and public key.
+type KEMPrivateKey struct {
+	kem    *KeyEncapsulation
+	public *KEMPublicKey
+}
+
+// GenerateKEMKey generates a fresh key pair for the KEM algorithm algName.
+func GenerateKEMKey(algName string) (*KEMPrivateKey, error) {
+	kem, err := NewKeyEncapsulation(algName)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := kem.GenerateKeyPair()
+	if err != nil {
+		kem.Clean()
+		return nil, err
+	}
+	return &KEMPrivateKey{
+		kem:    kem,
+		public: &KEMPublicKey{algName: algName, publicKey: publicKey},
+	}, nil
+}
+
+// NewKEMPrivateKey wraps an existing secret key/public key pair for the KEM
+// algorithm algName. Both keys are copied, and are checked to form a key pair
+// with KeyEncapsulation.ValidateKeyPair.
+func NewKEMPrivateKey(algName string, secretKey, publicKey []byte) (
+	*KEMPrivateKey, error,
+) {
+	public, err := NewKEMPublicKey(algName, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	kem, err := NewKeyEncapsulation(algName, WithSecretKey(secretKey))
+	if err != nil {
+		return nil, err
+	}
+	if err := kem.ValidateKeyPair(public.publicKey); err != nil {
+		kem.Clean()
+		return nil, err
+	}
+	return &KEMPrivateKey{kem: kem, public: public}, nil
+}
+
+// Algorithm returns the KEM algorithm name.
+func (priv *KEMPrivateKey) Algorithm() string {
+	return priv.public.algName
+}
+
+// Public returns the corresponding *KEMPublicKey.
+func (priv *KEMPrivateKey) Public() crypto.PublicKey {
+	return priv.public
+}
+
+// ExportSecretKey returns a copy of the secret key.
+func (priv *KEMPrivateKey) ExportSecretKey() []byte {
+	return priv.kem.ExportSecretKey()
+}
+
+// ExportSecretKeyTo copies the secret key into dst, see
+// KeyEncapsulation.ExportSecretKeyTo.
+func (priv *KEMPrivateKey) ExportSecretKeyTo(dst []byte) (int, error) {
+	return priv.kem.ExportSecretKeyTo(dst)
+}
+
+// Equal reports whether priv and x have the same algorithm and value.
+func (priv *KEMPrivateKey) Equal(x crypto.PrivateKey) bool {
+	xx, ok := x.(*KEMPrivateKey)
+	if !ok {
+		return false
+	}
+	return priv.public.Equal(xx.public) &&
+		subtle.ConstantTimeCompare(priv.kem.secretKey, xx.kem.secretKey) == 1
+}
+
+// Decapsulate recovers the shared secret from a ciphertext produced by
+// KEMPublicKey.Encapsulate.
+func (priv *KEMPrivateKey) Decapsulate(ciphertext []byte) ([]byte, error) {
+	return priv.kem.DecapSecret(ciphertext)
+}
+
+// Clean zeroes-in the secret key and frees the underlying KeyEncapsulation.
+func (priv *KEMPrivateKey) Clean() {
+	priv.kem.Clean()
+}
+
+/**************** END KEM keys ****************/
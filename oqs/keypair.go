@@ -0,0 +1,179 @@
+package oqs
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/**************** Key pair consistency ****************/
+
+// Lengths of the FIPS 203 and FIPS 204 secret key fields used below.
+const (
+	mlkemHashLength = 32 // H(ek) and z, at the end of an ML-KEM secret key
+	mldsaSeedLength = 32 // rho and K, at the start of an ML-DSA secret key
+	mldsaTrLength   = 64 // tr = H(pk), following rho and K
+)
+
+// Name prefixes of the liboqs algorithm families whose secret key layout is
+// known.
+const (
+	mlkemNamePrefix  = "ML-KEM-"
+	mldsaNamePrefix  = "ML-DSA-"
+	slhdsaNamePrefix = "SLH_DSA_"
+)
+
+// selfTestMessage is signed by Signature.ValidateKeyPair.
+const selfTestMessage = "liboqs-go key pair consistency self-test"
+
+// PublicKey returns the public key embedded in the secret key of the kem
+// receiver. Only ML-KEM secret keys embed the public key (FIPS 203); for the
+// other algorithms, ErrPublicKeyUnavailable is returned.
+func (kem *KeyEncapsulation) PublicKey() ([]byte, error) {
+	if kem.kem == nil {
+		return nil, newError("", "public key", ErrNotInitialized)
+	}
+	if err := kem.checkSecretKey("public key"); err != nil {
+		return nil, err
+	}
+	if !kem.isMLKEM() {
+		return nil, newError(kem.algDetails.Name, "public key",
+			ErrPublicKeyUnavailable)
+	}
+	// dk = dk_PKE || ek || H(ek) || z
+	end := len(kem.secretKey) - 2*mlkemHashLength
+	start := end - kem.algDetails.LengthPublicKey
+	return append([]byte(nil), kem.secretKey[start:end]...), nil
+}
+
+// ValidateKeyPair checks that publicKey matches the secret key of the kem
+// receiver, by comparing it to the embedded public key, if any, and by
+// decapsulating a secret encapsulated to publicKey. It returns an error
+// wrapping ErrKeyPairMismatch if they do not match.
+func (kem *KeyEncapsulation) ValidateKeyPair(publicKey []byte) error {
+	if kem.kem == nil {
+		return newError("", "validate key pair", ErrNotInitialized)
+	}
+	if err := kem.checkSecretKey("validate key pair"); err != nil {
+		return err
+	}
+	if embedded, err := kem.PublicKey(); err == nil &&
+		subtle.ConstantTimeCompare(embedded, publicKey) != 1 {
+		return newError(kem.algDetails.Name, "validate key pair",
+			ErrKeyPairMismatch)
+	}
+	ciphertext, sharedSecret, err := kem.EncapSecret(publicKey)
+	if err != nil {
+		return err
+	}
+	defer MemCleanse(sharedSecret)
+	decapsulated, err := kem.DecapSecret(ciphertext)
+	if err != nil {
+		return err
+	}
+	defer MemCleanse(decapsulated)
+	if subtle.ConstantTimeCompare(sharedSecret, decapsulated) != 1 {
+		return newError(kem.algDetails.Name, "validate key pair",
+			ErrKeyPairMismatch)
+	}
+	return nil
+}
+
+// PublicKey returns the public key of the secret key of the sig receiver.
+// SLH-DSA secret keys end with the public key (FIPS 205). ML-DSA secret keys
+// only embed its hash tr (FIPS 204), hence the public key is regenerated from
+// the seed if the key pair was generated by GenerateKeyPairFromSeed, and
+// otherwise recomputed from rho, s1 and s2, and checked against tr. For the
+// other algorithms, ErrPublicKeyUnavailable is returned.
+func (sig *Signature) PublicKey() ([]byte, error) {
+	if sig.sig == nil {
+		return nil, newError("", "public key", ErrNotInitialized)
+	}
+	if err := sig.checkSecretKey("public key"); err != nil {
+		return nil, err
+	}
+	switch {
+	case strings.HasPrefix(sig.algDetails.Name, slhdsaNamePrefix):
+		// SK.seed || SK.prf || PK.seed || PK.root
+		start := len(sig.secretKey) - sig.algDetails.LengthPublicKey
+		return append([]byte(nil), sig.secretKey[start:]...), nil
+	case sig.seed != nil:
+		other, err := NewSignature(sig.algDetails.Name)
+		if err != nil {
+			return nil, err
+		}
+		defer other.Clean()
+		publicKey, err := other.GenerateKeyPairFromSeed(sig.seed)
+		if err != nil {
+			return nil, err
+		}
+		if subtle.ConstantTimeCompare(other.secretKey, sig.secretKey) != 1 {
+			return nil, newError(sig.algDetails.Name, "public key",
+				ErrKeyPairMismatch)
+		}
+		return publicKey, nil
+	case strings.HasPrefix(sig.algDetails.Name, mldsaNamePrefix):
+		publicKey, ok := mldsaPublicKey(sig.algDetails.Name, sig.secretKey)
+		if !ok {
+			break
+		}
+		// rho || K || tr || s1 || s2 || t0, with tr = H(pk)
+		tr := make([]byte, mldsaTrLength)
+		sha3.ShakeSum256(tr, publicKey)
+		start := 2 * mldsaSeedLength
+		if !bytes.Equal(tr, sig.secretKey[start:start+mldsaTrLength]) {
+			return nil, newError(sig.algDetails.Name, "public key",
+				ErrKeyPairMismatch)
+		}
+		return publicKey, nil
+	}
+	return nil, newError(sig.algDetails.Name, "public key",
+		ErrPublicKeyUnavailable)
+}
+
+// ValidateKeyPair checks that publicKey matches the secret key of the sig
+// receiver, by comparing it to the embedded public key or public key hash, if
+// any, and by verifying a signature with publicKey. It returns an error
+// wrapping ErrKeyPairMismatch if they do not match.
+func (sig *Signature) ValidateKeyPair(publicKey []byte) error {
+	if sig.sig == nil {
+		return newError("", "validate key pair", ErrNotInitialized)
+	}
+	if err := sig.checkSecretKey("validate key pair"); err != nil {
+		return err
+	}
+	if len(publicKey) != sig.algDetails.LengthPublicKey {
+		return newError(sig.algDetails.Name, "validate key pair",
+			ErrInvalidPublicKeyLength)
+	}
+	if strings.HasPrefix(sig.algDetails.Name, mldsaNamePrefix) {
+		// rho || K || tr || s1 || s2 || t0, with tr = H(pk)
+		tr := make([]byte, mldsaTrLength)
+		sha3.ShakeSum256(tr, publicKey)
+		start := 2 * mldsaSeedLength
+		if !bytes.Equal(tr, sig.secretKey[start:start+mldsaTrLength]) {
+			return newError(sig.algDetails.Name, "validate key pair",
+				ErrKeyPairMismatch)
+		}
+	} else if embedded, err := sig.PublicKey(); err == nil &&
+		subtle.ConstantTimeCompare(embedded, publicKey) != 1 {
+		return newError(sig.algDetails.Name, "validate key pair",
+			ErrKeyPairMismatch)
+	}
+	message := []byte(selfTestMessage)
+	signature, err := sig.Sign(message)
+	if err != nil {
+		return err
+	}
+	if isValid, err := sig.Verify(message, signature, publicKey); err != nil {
+		return err
+	} else if !isValid {
+		return newError(sig.algDetails.Name, "validate key pair",
+			ErrKeyPairMismatch)
+	}
+	return nil
+}
+
+/**************** END Key pair consistency ****************/
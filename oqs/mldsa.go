@@ -0,0 +1,216 @@
+package oqs
+
+import (
+	"golang.org/x/crypto/sha3"
+)
+
+/**************** ML-DSA public key derivation ****************/
+
+// ML-DSA parameters shared by all parameter sets (FIPS 204).
+const (
+	mldsaQ          = 8380417
+	mldsaN          = 256
+	mldsaD          = 13       // dropped bits of t
+	mldsaQInv       = 58728449 // q^-1 mod 2^32
+	mldsaZeta       = 1753     // 512th root of unity mod q
+	mldsaT1PolySize = 320      // SimpleBitPack of t1, 10 bits per coefficient
+)
+
+// mldsaParams are the dimensions of the matrix A and the bound eta of the
+// secret vectors of an ML-DSA parameter set.
+type mldsaParams struct {
+	k, l, eta int
+}
+
+// mldsaParamSets lists the ML-DSA parameter sets by liboqs name.
+var mldsaParamSets = map[string]mldsaParams{
+	"ML-DSA-44": {k: 4, l: 4, eta: 2},
+	"ML-DSA-65": {k: 6, l: 5, eta: 4},
+	"ML-DSA-87": {k: 8, l: 7, eta: 2},
+}
+
+// mldsaPoly is a polynomial of R_q, with coefficients in (-9q, 9q).
+type mldsaPoly [mldsaN]int32
+
+// mldsaZetas are the powers zeta^BitRev_8(i) of the NTT, in Montgomery form,
+// and mldsaInvNTTFactor is 2^64/256 mod q, which makes the inverse NTT undo
+// both the NTT and a Montgomery multiplication.
+var mldsaZetas, mldsaInvNTTFactor = func() ([mldsaN]int32, int32) {
+	mulMod := func(a, b uint64) uint64 { return a * b % mldsaQ }
+	var zetas [mldsaN]int32
+	for i := range zetas {
+		var brv uint
+		for bit := 0; bit < 8; bit++ {
+			brv |= uint(i>>bit&1) << (7 - bit)
+		}
+		z, mont := uint64(1), uint64(1<<32%mldsaQ)
+		for j := uint(0); j < brv; j++ {
+			z = mulMod(z, mldsaZeta)
+		}
+		zetas[i] = int32(mulMod(z, mont))
+	}
+	f := uint64(1)
+	for i := 0; i < 56; i++ {
+		f = mulMod(f, 2)
+	}
+	return zetas, int32(f)
+}()
+
+// mldsaMontgomeryReduce returns a * 2^-32 mod q, in (-q, q), for |a| < 2^31 q.
+func mldsaMontgomeryReduce(a int64) int32 {
+	t := int32(int64(int32(a)) * mldsaQInv)
+	return int32((a - int64(t)*mldsaQ) >> 32)
+}
+
+// mldsaReduce32 returns a mod q, in [-6283009, 6283008], for a < 2^31 - 2^22.
+func mldsaReduce32(a int32) int32 {
+	t := (a + 1<<22) >> 23
+	return a - t*mldsaQ
+}
+
+// ntt transforms p into the NTT domain, in place (FIPS 204, algorithm 41).
+func (p *mldsaPoly) ntt() {
+	k := 0
+	for length := 128; length > 0; length >>= 1 {
+		for start := 0; start < mldsaN; start += 2 * length {
+			k++
+			zeta := int64(mldsaZetas[k])
+			for j := start; j < start+length; j++ {
+				t := mldsaMontgomeryReduce(zeta * int64(p[j+length]))
+				p[j+length] = p[j] - t
+				p[j] += t
+			}
+		}
+	}
+}
+
+// invNTT transforms p back from the NTT domain, in place, and multiplies it
+// by 2^32 (FIPS 204, algorithm 42).
+func (p *mldsaPoly) invNTT() {
+	k := mldsaN
+	for length := 1; length < mldsaN; length <<= 1 {
+		for start := 0; start < mldsaN; start += 2 * length {
+			k--
+			zeta := -int64(mldsaZetas[k])
+			for j := start; j < start+length; j++ {
+				t := p[j]
+				p[j] = t + p[j+length]
+				p[j+length] = mldsaMontgomeryReduce(zeta *
+					int64(t-p[j+length]))
+			}
+		}
+	}
+	for j := range p {
+		p[j] = mldsaMontgomeryReduce(int64(mldsaInvNTTFactor) * int64(p[j]))
+	}
+}
+
+// mldsaExpandA returns the entry A[r][s] of the matrix expanded from rho, in
+// the NTT domain (FIPS 204, algorithms 30 and 32).
+func mldsaExpandA(rho []byte, r, s int) *mldsaPoly {
+	xof := sha3.NewShake128()
+	xof.Write(rho)
+	xof.Write([]byte{byte(s), byte(r)})
+	var p mldsaPoly
+	var buf [3 * 56]byte
+	for i := 0; i < mldsaN; {
+		xof.Read(buf[:])
+		for b := 0; b < len(buf) && i < mldsaN; b += 3 {
+			t := int32(buf[b]) | int32(buf[b+1])<<8 | int32(buf[b+2]&0x7f)<<16
+			if t < mldsaQ {
+				p[i] = t
+				i++
+			}
+		}
+	}
+	return &p
+}
+
+// mldsaUnpackEta decodes a polynomial of s1 or s2 with coefficients in
+// [-eta, eta] (FIPS 204, algorithm 19), from eta*32+32 bytes for eta = 2 and
+// 128 bytes for eta = 4.
+func mldsaUnpackEta(b []byte, eta int) *mldsaPoly {
+	var p mldsaPoly
+	if eta == 2 {
+		for i := 0; i < mldsaN/8; i++ {
+			v := uint32(b[3*i]) | uint32(b[3*i+1])<<8 | uint32(b[3*i+2])<<16
+			for j := 0; j < 8; j++ {
+				p[8*i+j] = int32(eta) - int32(v>>(3*j)&7)
+			}
+		}
+		return &p
+	}
+	for i := 0; i < mldsaN/2; i++ {
+		p[2*i] = int32(eta) - int32(b[i]&0x0f)
+		p[2*i+1] = int32(eta) - int32(b[i]>>4)
+	}
+	return &p
+}
+
+// mldsaPublicKey derives the public key rho || SimpleBitPack(t1) of an ML-DSA
+// secret key rho || K || tr || s1 || s2 || t0, by recomputing
+// t = NTT^-1(A * NTT(s1)) + s2 and its high bits t1 = Power2Round(t), as the
+// key generation of FIPS 204 (algorithm 6) does. It returns false if the
+// parameter set is unknown.
+func mldsaPublicKey(algName string, secretKey []byte) ([]byte, bool) {
+	params, ok := mldsaParamSets[algName]
+	if !ok {
+		return nil, false
+	}
+	polyEtaSize := 128
+	if params.eta == 2 {
+		polyEtaSize = 96
+	}
+	rho := secretKey[:mldsaSeedLength]
+	offset := 2*mldsaSeedLength + mldsaTrLength
+	s1 := make([]*mldsaPoly, params.l)
+	for j := range s1 {
+		s1[j] = mldsaUnpackEta(secretKey[offset:], params.eta)
+		s1[j].ntt()
+		offset += polyEtaSize
+	}
+	defer func() {
+		for _, p := range s1 {
+			clear(p[:])
+		}
+	}()
+
+	publicKey := make([]byte, mldsaSeedLength, mldsaSeedLength+
+		params.k*mldsaT1PolySize)
+	copy(publicKey, rho)
+	var t mldsaPoly
+	for i := 0; i < params.k; i++ {
+		clear(t[:])
+		for j := 0; j < params.l; j++ {
+			a := mldsaExpandA(rho, i, j)
+			for n := range t {
+				t[n] += mldsaMontgomeryReduce(int64(a[n]) * int64(s1[j][n]))
+			}
+		}
+		for n := range t {
+			t[n] = mldsaReduce32(t[n])
+		}
+		t.invNTT()
+		s2 := mldsaUnpackEta(secretKey[offset+i*polyEtaSize:], params.eta)
+		var t1 [4]int32
+		for n := range t {
+			c := mldsaReduce32(t[n] + s2[n])
+			c += (c >> 31) & mldsaQ
+			// Power2Round: the high bits of c
+			t1[n%4] = (c + 1<<(mldsaD-1) - 1) >> mldsaD
+			if n%4 == 3 {
+				publicKey = append(publicKey,
+					byte(t1[0]),
+					byte(t1[0]>>8|t1[1]<<2),
+					byte(t1[1]>>6|t1[2]<<4),
+					byte(t1[2]>>4|t1[3]<<6),
+					byte(t1[3]>>2))
+			}
+		}
+		clear(s2[:])
+	}
+	clear(t[:])
+	return publicKey, true
+}
+
+/**************** END ML-DSA public key derivation ****************/
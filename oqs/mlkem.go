@@ -0,0 +1,90 @@
+package oqs
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+/**************** ML-KEM input validation ****************/
+
+// ML-KEM parameters shared by all parameter sets (FIPS 203).
+const (
+	mlkemQ              = 3329
+	mlkemPolyBytes      = 384 // ByteEncode_12 of a polynomial
+	mlkemPublicSeedSize = 32  // rho, at the end of an encapsulation key
+)
+
+// isMLKEM reports whether the kem receiver implements ML-KEM.
+func (kem *KeyEncapsulation) isMLKEM() bool {
+	return strings.HasPrefix(kem.algDetails.Name, mlkemNamePrefix)
+}
+
+// ValidatePublicKey performs the encapsulation key checks of FIPS 203, section
+// 7.2, on publicKey: the length check and, for ML-KEM, the modulus check,
+// i.e. that every encoded coefficient is reduced modulo q. It returns an
+// error wrapping ErrInvalidPublicKeyLength or ErrInvalidPublicKey. Other
+// algorithms only have their length checked.
+func (kem *KeyEncapsulation) ValidatePublicKey(publicKey []byte) error {
+	if kem.kem == nil {
+		return newError("", "validate public key", ErrNotInitialized)
+	}
+	if len(publicKey) != kem.algDetails.LengthPublicKey {
+		return newError(kem.algDetails.Name, "validate public key",
+			ErrInvalidPublicKeyLength)
+	}
+	if kem.isMLKEM() && !mlkemModulusCheck(publicKey) {
+		return newError(kem.algDetails.Name, "validate public key",
+			ErrInvalidPublicKey)
+	}
+	return nil
+}
+
+// ValidateSecretKey performs the decapsulation key checks of FIPS 203,
+// section 7.3, on the secret key of the kem receiver: the length check and,
+// for ML-KEM, the hash check, i.e. that the embedded hash of the
+// encapsulation key matches it. It returns an error wrapping
+// ErrInvalidSecretKeyLength or ErrInvalidSecretKey. Other algorithms only have
+// their length checked.
+func (kem *KeyEncapsulation) ValidateSecretKey() error {
+	if kem.kem == nil {
+		return newError("", "validate secret key", ErrNotInitialized)
+	}
+	if err := kem.checkSecretKey("validate secret key"); err != nil {
+		return err
+	}
+	if !kem.isMLKEM() {
+		return nil
+	}
+	// dk = dk_PKE || ek || H(ek) || z
+	end := len(kem.secretKey) - 2*mlkemHashLength
+	start := end - kem.algDetails.LengthPublicKey
+	hash := sha3.Sum256(kem.secretKey[start:end])
+	if subtle.ConstantTimeCompare(hash[:],
+		kem.secretKey[end:end+mlkemHashLength]) != 1 {
+		return newError(kem.algDetails.Name, "validate secret key",
+			ErrInvalidSecretKey)
+	}
+	return nil
+}
+
+// mlkemModulusCheck reports whether ByteEncode_12(ByteDecode_12(t)) == t for
+// the encoded vector t of an ML-KEM encapsulation key, i.e. whether every
+// 12-bit coefficient is less than q.
+func mlkemModulusCheck(publicKey []byte) bool {
+	t := publicKey[:len(publicKey)-mlkemPublicSeedSize]
+	if len(t)%mlkemPolyBytes != 0 {
+		return false
+	}
+	for i := 0; i+3 <= len(t); i += 3 {
+		a := uint16(t[i]) | uint16(t[i+1]&0x0f)<<8
+		b := uint16(t[i+1])>>4 | uint16(t[i+2])<<4
+		if a >= mlkemQ || b >= mlkemQ {
+			return false
+		}
+	}
+	return true
+}
+
+/**************** END ML-KEM input validation ****************/
@@ -3,15 +3,24 @@ package oqs // import "github.com/open-quantum-safe/liboqs-go/oqs"
 
 /*
 #cgo pkg-config: liboqs-go
+#include <stdlib.h>
 #include <oqs/oqs.h>
 typedef void (*rand_algorithm_ptr)(uint8_t*, size_t);
 void randAlgorithmPtr_cgo(uint8_t*, size_t);
+void threadRandAlgorithm_cgo(uint8_t*, size_t);
+void setReaderRand_cgo(uintptr_t);
+OQS_STATUS selectRandAlgorithm_cgo(const char*);
+OQS_STATUS sigKeypairFromSeed_cgo(const OQS_SIG*, uint8_t*, uint8_t*,
+	const uint8_t*, size_t);
 */
 import "C"
 
 import (
-	"errors"
 	"fmt"
+	"io"
+	"runtime"
+	"runtime/cgo"
+	"sync"
 	"unsafe"
 )
 
@@ -26,11 +35,112 @@ func LiboqsVersion() string {
 // OQS_MEM_cleanse() function. Use it to clean "hot" memory areas, such as
 // secret keys etc.
 func MemCleanse(v []byte) {
-	C.OQS_MEM_cleanse(unsafe.Pointer(&v[0]), C.size_t(len(v)))
+	if len(v) == 0 {
+		return
+	}
+	C.OQS_MEM_cleanse(unsafe.Pointer(bytesPtr(v)), C.size_t(len(v)))
+}
+
+// bytesPtr returns a pointer to the first byte of b, to be passed to liboqs
+// together with len(b), or NULL if b is empty. liboqs accepts NULL for
+// zero-length inputs, such as empty messages and context strings, whereas
+// &b[0] panics on them.
+func bytesPtr(b []byte) *C.uint8_t {
+	if len(b) == 0 {
+		return nil
+	}
+	return (*C.uint8_t)(unsafe.Pointer(&b[0]))
 }
 
 /**************** END Misc functions ****************/
 
+/**************** Options ****************/
+
+// Option configures a KeyEncapsulation or a Signature created by
+// NewKeyEncapsulation or NewSignature.
+type Option func(*options)
+
+// options collects the settings applied by a list of Option values.
+type options struct {
+	secretKey []byte
+	borrowed  bool // the secret key is used in place, see WithBorrowedSecretKey
+	rand      io.Reader
+	strict    bool
+}
+
+// newOptions applies opts on top of the default settings.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// ownedSecretKey returns the secret key to be copied by Init, i.e. nil if it
+// is borrowed.
+func (o options) ownedSecretKey() []byte {
+	if o.borrowed {
+		return nil
+	}
+	return o.secretKey
+}
+
+// WithSecretKey imports an existing secret key, as if it were passed to the
+// Init method. Without it, the caller must invoke GenerateKeyPair before any
+// operation that requires a secret key. The secret key is copied, hence the
+// caller keeps ownership of secretKey: it is neither modified nor cleansed.
+func WithSecretKey(secretKey []byte) Option {
+	return func(o *options) {
+		o.secretKey = secretKey
+		o.borrowed = false
+	}
+}
+
+// WithBorrowedSecretKey is like WithSecretKey, but a KeyEncapsulation or a
+// Signature uses secretKey in place instead of copying it into a
+// SecretBuffer, e.g. when the secret key already lives in secure memory. The
+// caller must keep secretKey unmodified until Clean, and remains responsible
+// for cleansing it afterwards, as Clean does not. The other constructors copy
+// secretKey as with WithSecretKey. NewKeyEncapsulation and NewSignature
+// return an error wrapping ErrInvalidSecretKeyLength if secretKey does not
+// have the length of the secret keys of the algorithm.
+func WithBorrowedSecretKey(secretKey []byte) Option {
+	return func(o *options) {
+		o.secretKey = secretKey
+		o.borrowed = true
+	}
+}
+
+// WithRand draws the randomness of the GenerateKeyPair, EncapSecret, Sign and
+// SignWithCtxStr operations from r instead of OQS_randombytes, e.g. from a
+// deterministic RNG in tests. Unlike RandomBytesCustomAlgorithm, it does not
+// affect other goroutines: while such an operation runs, only the requests of
+// its own thread are served by r. The operations using a reader, and their
+// reads from it, are serialized with each other, so r need not be safe for
+// concurrent use, but must not itself run operations configured with WithRand.
+// If r fails, the operation returns an error wrapping ErrRandFailure.
+func WithRand(r io.Reader) Option {
+	return func(o *options) {
+		o.rand = r
+	}
+}
+
+// WithStrictValidation makes a KeyEncapsulation validate its inputs as
+// required by FIPS 203 before every use: EncapSecret runs ValidatePublicKey on
+// the public key, as does EncapSecretDeterministic, and DecapSecret runs
+// ValidateSecretKey on the secret key.
+// It has no effect on a Signature.
+func WithStrictValidation() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+/**************** END Options ****************/
+
 /**************** KEMs ****************/
 
 // List of enabled KEM algorithms, populated by init().
@@ -46,7 +156,9 @@ func MaxNumberKEMs() int {
 
 // IsKEMEnabled returns true if a KEM algorithm is enabled, and false otherwise.
 func IsKEMEnabled(algName string) bool {
-	result := C.OQS_KEM_alg_is_enabled(C.CString(algName))
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	result := C.OQS_KEM_alg_is_enabled(cAlgName)
 	return result != 0
 }
 
@@ -64,7 +176,7 @@ func IsKEMSupported(algName string) bool {
 // KEMName returns the KEM algorithm name from its corresponding numerical ID.
 func KEMName(algID int) (string, error) {
 	if algID >= MaxNumberKEMs() {
-		return "", errors.New("algorithm ID out of range")
+		return "", newError("", "name", ErrAlgorithmIDOutOfRange)
 	}
 	return C.GoString(C.OQS_KEM_alg_identifier(C.size_t(algID))), nil
 }
@@ -82,6 +194,11 @@ func EnabledKEMs() []string {
 // Initializes liboqs and the lists enabledKEMs and supportedKEMs.
 func init() {
 	C.OQS_init()
+	// all the RNG requests go through threadRandAlgorithm_cgo, which
+	// forwards them to the algorithm selected by RandomBytesSwitchAlgorithm
+	// or RandomBytesCustomAlgorithm unless the calling thread overrides it
+	C.OQS_randombytes_custom_algorithm(
+		(C.rand_algorithm_ptr)(unsafe.Pointer(C.threadRandAlgorithm_cgo)))
 	for i := 0; i < MaxNumberKEMs(); i++ {
 		KEMName, _ := KEMName(i)
 		supportedKEMs = append(supportedKEMs, KEMName)
@@ -105,6 +222,9 @@ type KeyEncapsulationDetails struct {
 	LengthSecretKey    int
 	LengthCiphertext   int
 	LengthSharedSecret int
+	LengthKeypairSeed  int  // 0 if deterministic key generation is unsupported
+	LengthEncapsSeed   int  // 0 if deterministic encapsulation is unsupported
+	SecretKeyLocked    bool // whether the secret key is locked into RAM
 }
 
 // String converts the KEM algorithm details to a string representation. Use
@@ -118,7 +238,10 @@ func (kemDetails KeyEncapsulationDetails) String() string {
 		"Length public key (bytes): %d\n"+
 		"Length secret key (bytes): %d\n"+
 		"Length ciphertext (bytes): %d\n"+
-		"Length shared secret (bytes): %d",
+		"Length shared secret (bytes): %d\n"+
+		"Length keypair seed (bytes): %d\n"+
+		"Length encapsulation seed (bytes): %d\n"+
+		"Secret key locked: %v",
 		kemDetails.Name,
 		kemDetails.Version,
 		kemDetails.ClaimedNISTLevel,
@@ -126,14 +249,23 @@ func (kemDetails KeyEncapsulationDetails) String() string {
 		kemDetails.LengthPublicKey,
 		kemDetails.LengthSecretKey,
 		kemDetails.LengthCiphertext,
-		kemDetails.LengthSharedSecret)
+		kemDetails.LengthSharedSecret,
+		kemDetails.LengthKeypairSeed,
+		kemDetails.LengthEncapsSeed,
+		kemDetails.SecretKeyLocked)
 }
 
 // KeyEncapsulation defines the KEM main data structure.
 type KeyEncapsulation struct {
 	kem        *C.OQS_KEM
+	secretBuf  *SecretBuffer // holds secretKey and seed
 	secretKey  []byte
+	seed       []byte    // set by GenerateKeyPairFromSeed
+	rand       io.Reader // set by WithRand
+	strict     bool      // set by WithStrictValidation
 	algDetails KeyEncapsulationDetails
+	release    func() // set by KEMPool.Get, releases the place in the pool
+	finalizer  bool   // true if a finalizer was set by NewKeyEncapsulation
 }
 
 // String converts the KEM algorithm name to a string representation. Use this
@@ -143,20 +275,66 @@ func (kem KeyEncapsulation) String() string {
 		kem.algDetails.Name)
 }
 
+// NewKeyEncapsulation allocates and initializes a KEM with an algorithm name,
+// configured by opts (e.g. WithSecretKey). The caller should invoke
+// KeyEncapsulation.Clean once done; a finalizer frees the underlying liboqs
+// object and cleanses the secret key if the caller forgets to.
+func NewKeyEncapsulation(algName string, opts ...Option) (*KeyEncapsulation,
+	error,
+) {
+	o := newOptions(opts)
+	kem := new(KeyEncapsulation)
+	if err := kem.Init(algName, o.ownedSecretKey()); err != nil {
+		return nil, err
+	}
+	if o.borrowed {
+		// a borrowed secret key is used in place, hence checked up front
+		if len(o.secretKey) != kem.algDetails.LengthSecretKey {
+			kem.Clean()
+			return nil, newError(kem.algDetails.Name, "init",
+				ErrInvalidSecretKeyLength)
+		}
+		kem.secretKey = o.secretKey
+	}
+	kem.rand = o.rand
+	kem.strict = o.strict
+	kem.finalizer = true
+	runtime.SetFinalizer(kem, (*KeyEncapsulation).Clean)
+	return kem, nil
+}
+
 // Init initializes the KEM data structure with an algorithm name and a secret
 // key. If the secret key is null, then the user must invoke the
 // KeyEncapsulation.GenerateKeyPair method to generate the pair of
-// secret key/public key.
+// secret key/public key. The secret key is copied into a SecretBuffer, hence
+// the caller may cleanse its own copy. Init may be invoked again on an
+// initialized receiver, which releases the previous liboqs object.
 func (kem *KeyEncapsulation) Init(algName string, secretKey []byte) error {
 	if !IsKEMEnabled(algName) {
 		// perhaps it's supported
 		if IsKEMSupported(algName) {
-			return errors.New(`"` + algName + `" KEM is not enabled by OQS`)
+			return newError(algName, "init", ErrAlgorithmNotEnabled)
 		}
-		return errors.New(`"` + algName + `" KEM is not supported by OQS`)
+		return newError(algName, "init", ErrAlgorithmNotSupported)
 	}
-	kem.kem = C.OQS_KEM_new(C.CString(algName))
-	kem.secretKey = secretKey
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	handle := C.OQS_KEM_new(cAlgName)
+	if handle == nil {
+		return newError(algName, "init", ErrLiboqsFailure)
+	}
+	var err error
+	kem.secretBuf, kem.secretKey, kem.seed, err = replaceSecretKey(
+		kem.secretBuf, secretKey, len(secretKey), nil)
+	if err != nil {
+		C.OQS_KEM_free(handle)
+		return newError(algName, "init", err)
+	}
+	// re-initialization releases the previous liboqs object
+	if kem.kem != nil {
+		C.OQS_KEM_free(kem.kem)
+	}
+	kem.kem = handle
 	kem.algDetails.Name = C.GoString(kem.kem.method_name)
 	kem.algDetails.Version = C.GoString(kem.kem.alg_version)
 	kem.algDetails.ClaimedNISTLevel = int(kem.kem.claimed_nist_level)
@@ -165,12 +343,18 @@ func (kem *KeyEncapsulation) Init(algName string, secretKey []byte) error {
 	kem.algDetails.LengthSecretKey = int(kem.kem.length_secret_key)
 	kem.algDetails.LengthCiphertext = int(kem.kem.length_ciphertext)
 	kem.algDetails.LengthSharedSecret = int(kem.kem.length_shared_secret)
+	kem.algDetails.LengthKeypairSeed = int(kem.kem.length_keypair_seed)
+	kem.algDetails.LengthEncapsSeed = int(kem.kem.length_encaps_seed)
 	return nil
 }
 
-// Details returns the KEM algorithm details.
+// Details returns the KEM algorithm details. SecretKeyLocked reports whether
+// the secret key, if any, is locked into RAM, see SecretBuffer.Locked; it is
+// false for a borrowed secret key.
 func (kem *KeyEncapsulation) Details() KeyEncapsulationDetails {
-	return kem.algDetails
+	details := kem.algDetails
+	details.SecretKeyLocked = kem.secretBuf.Locked()
+	return details
 }
 
 // GenerateKeyPair generates a pair of secret key/public key and returns the
@@ -178,25 +362,113 @@ func (kem *KeyEncapsulation) Details() KeyEncapsulationDetails {
 // is not directly accessible, unless one exports it with
 // KeyEncapsulation.ExportSecretKey method.
 func (kem *KeyEncapsulation) GenerateKeyPair() ([]byte, error) {
+	if kem.kem == nil {
+		return nil, newError("", "keypair", ErrNotInitialized)
+	}
+
+	publicKey := make([]byte, kem.algDetails.LengthPublicKey)
+	var err error
+	kem.secretBuf, kem.secretKey, kem.seed, err = replaceSecretKey(
+		kem.secretBuf, nil, kem.algDetails.LengthSecretKey, nil)
+	if err != nil {
+		return nil, newError(kem.algDetails.Name, "keypair", err)
+	}
+
+	var rv C.OQS_STATUS
+	randErr := withRand(kem.rand, func() {
+		rv = C.OQS_KEM_keypair(
+			kem.kem,
+			bytesPtr(publicKey),
+			bytesPtr(kem.secretKey),
+		)
+	})
+	runtime.KeepAlive(kem)
+
+	if randErr != nil {
+		kem.secretBuf.Destroy()
+		kem.secretBuf, kem.secretKey = nil, nil
+		return nil, newError(kem.algDetails.Name, "keypair", randErr)
+	}
+	if rv != C.OQS_SUCCESS {
+		return nil, newStatusError(kem.algDetails.Name, "keypair", int(rv))
+	}
+
+	return publicKey, nil
+}
+
+// GenerateKeyPairFromSeed deterministically generates a pair of secret
+// key/public key from a seed of Details().LengthKeypairSeed bytes, e.g. the
+// 64-byte seed d || z of ML-KEM (FIPS 203), and returns the public key. The
+// same seed always yields the same key pair, hence the seed may be stored
+// instead of the expanded secret key. A copy of the seed is kept inside the
+// kem receiver, so that the secret key can be encoded in the PKCS#8 seed
+// format.
+func (kem *KeyEncapsulation) GenerateKeyPairFromSeed(seed []byte) ([]byte,
+	error,
+) {
+	if kem.kem == nil {
+		return nil, newError("", "keypair", ErrNotInitialized)
+	}
+
+	if kem.algDetails.LengthKeypairSeed == 0 {
+		return nil, newError(kem.algDetails.Name, "keypair",
+			ErrDerandNotSupported)
+	}
+	if len(seed) != kem.algDetails.LengthKeypairSeed {
+		return nil, newError(kem.algDetails.Name, "keypair",
+			ErrInvalidSeedLength)
+	}
+
 	publicKey := make([]byte, kem.algDetails.LengthPublicKey)
-	kem.secretKey = make([]byte, kem.algDetails.LengthSecretKey)
+	var err error
+	kem.secretBuf, kem.secretKey, kem.seed, err = replaceSecretKey(
+		kem.secretBuf, nil, kem.algDetails.LengthSecretKey, seed)
+	if err != nil {
+		return nil, newError(kem.algDetails.Name, "keypair", err)
+	}
 
-	rv := C.OQS_KEM_keypair(
+	rv := C.OQS_KEM_keypair_derand(
 		kem.kem,
-		(*C.uint8_t)(unsafe.Pointer(&publicKey[0])),
-		(*C.uint8_t)(unsafe.Pointer(&kem.secretKey[0])),
+		bytesPtr(publicKey),
+		bytesPtr(kem.secretKey),
+		bytesPtr(seed),
 	)
+	runtime.KeepAlive(kem)
 
 	if rv != C.OQS_SUCCESS {
-		return nil, errors.New("can not generate keypair")
+		return nil, newStatusError(kem.algDetails.Name, "keypair", int(rv))
 	}
 
 	return publicKey, nil
 }
 
-// ExportSecretKey exports the corresponding secret key from the kem receiver.
+// ExportSecretKey exports a copy of the secret key of the kem receiver. The
+// secret key itself is kept in a SecretBuffer, hence the copy lives on the Go
+// heap; the caller should cleanse it with MemCleanse once done.
 func (kem *KeyEncapsulation) ExportSecretKey() []byte {
-	return kem.secretKey
+	if kem.secretKey == nil {
+		return nil
+	}
+	return append([]byte(nil), kem.secretKey...)
+}
+
+// ExportSecretKeyTo copies the secret key of the kem receiver into dst, e.g.
+// a SecretBuffer, without an intermediate copy on the Go heap, and returns the
+// number of bytes copied. It returns an error wrapping io.ErrShortBuffer if
+// dst is too short.
+func (kem *KeyEncapsulation) ExportSecretKeyTo(dst []byte) (int, error) {
+	if kem.kem == nil {
+		return 0, newError("", "export secret key", ErrNotInitialized)
+	}
+	if len(kem.secretKey) == 0 {
+		return 0, newError(kem.algDetails.Name, "export secret key",
+			ErrNoSecretKey)
+	}
+	if len(dst) < len(kem.secretKey) {
+		return 0, newError(kem.algDetails.Name, "export secret key",
+			io.ErrShortBuffer)
+	}
+	return copy(dst, kem.secretKey), nil
 }
 
 // EncapSecret encapsulates a secret using a public key and returns the
@@ -204,22 +476,119 @@ func (kem *KeyEncapsulation) ExportSecretKey() []byte {
 func (kem *KeyEncapsulation) EncapSecret(publicKey []byte) (ciphertext,
 	sharedSecret []byte, err error,
 ) {
+	if kem.kem == nil {
+		return nil, nil, newError("", "encaps", ErrNotInitialized)
+	}
+	sharedSecret = make([]byte, kem.algDetails.LengthSharedSecret)
+	ciphertext, err = kem.encapSecret(publicKey, sharedSecret)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ciphertext, sharedSecret, nil
+}
+
+// EncapSecretBuffer is like EncapSecret, but returns the shared secret in a
+// SecretBuffer, which the caller must destroy once done.
+func (kem *KeyEncapsulation) EncapSecretBuffer(publicKey []byte) (
+	ciphertext []byte, sharedSecret *SecretBuffer, err error,
+) {
+	if kem.kem == nil {
+		return nil, nil, newError("", "encaps", ErrNotInitialized)
+	}
+	sharedSecret, err = NewSecretBuffer(kem.algDetails.LengthSharedSecret)
+	if err != nil {
+		return nil, nil, newError(kem.algDetails.Name, "encaps", err)
+	}
+	ciphertext, err = kem.encapSecret(publicKey, sharedSecret.Bytes())
+	if err != nil {
+		sharedSecret.Destroy()
+		return nil, nil, err
+	}
+	return ciphertext, sharedSecret, nil
+}
+
+// encapSecret encapsulates a secret using a public key, writes the shared
+// secret into sharedSecret and returns the ciphertext.
+func (kem *KeyEncapsulation) encapSecret(publicKey,
+	sharedSecret []byte,
+) ([]byte, error) {
 	if len(publicKey) != kem.algDetails.LengthPublicKey {
-		return nil, nil, errors.New("incorrect public key length")
+		return nil, newError(kem.algDetails.Name, "encaps",
+			ErrInvalidPublicKeyLength)
+	}
+	if kem.strict {
+		if err := kem.ValidatePublicKey(publicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	ciphertext := make([]byte, kem.algDetails.LengthCiphertext)
+
+	var rv C.OQS_STATUS
+	randErr := withRand(kem.rand, func() {
+		rv = C.OQS_KEM_encaps(
+			kem.kem,
+			bytesPtr(ciphertext),
+			bytesPtr(sharedSecret),
+			bytesPtr(publicKey),
+		)
+	})
+	runtime.KeepAlive(kem)
+
+	if randErr != nil {
+		MemCleanse(sharedSecret)
+		return nil, newError(kem.algDetails.Name, "encaps", randErr)
+	}
+	if rv != C.OQS_SUCCESS {
+		return nil, newStatusError(kem.algDetails.Name, "encaps", int(rv))
+	}
+
+	return ciphertext, nil
+}
+
+// EncapSecretDeterministic is like EncapSecret, but derives the ciphertext
+// and the shared secret from a seed of Details().LengthEncapsSeed bytes, e.g.
+// the 32-byte message m of ML-KEM (FIPS 203), instead of the RNG. It is meant
+// for known-answer tests; the seed must be secret and never reused.
+func (kem *KeyEncapsulation) EncapSecretDeterministic(publicKey,
+	seed []byte,
+) (ciphertext, sharedSecret []byte, err error) {
+	if kem.kem == nil {
+		return nil, nil, newError("", "encaps", ErrNotInitialized)
+	}
+
+	if kem.algDetails.LengthEncapsSeed == 0 {
+		return nil, nil, newError(kem.algDetails.Name, "encaps",
+			ErrDerandNotSupported)
+	}
+	if len(seed) != kem.algDetails.LengthEncapsSeed {
+		return nil, nil, newError(kem.algDetails.Name, "encaps",
+			ErrInvalidSeedLength)
+	}
+	if len(publicKey) != kem.algDetails.LengthPublicKey {
+		return nil, nil, newError(kem.algDetails.Name, "encaps",
+			ErrInvalidPublicKeyLength)
+	}
+	if kem.strict {
+		if err := kem.ValidatePublicKey(publicKey); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	ciphertext = make([]byte, kem.algDetails.LengthCiphertext)
 	sharedSecret = make([]byte, kem.algDetails.LengthSharedSecret)
 
-	rv := C.OQS_KEM_encaps(
+	rv := C.OQS_KEM_encaps_derand(
 		kem.kem,
-		(*C.uint8_t)(unsafe.Pointer(&ciphertext[0])),
-		(*C.uint8_t)(unsafe.Pointer(&sharedSecret[0])),
-		(*C.uint8_t)(unsafe.Pointer(&publicKey[0])),
+		bytesPtr(ciphertext),
+		bytesPtr(sharedSecret),
+		bytesPtr(publicKey),
+		bytesPtr(seed),
 	)
+	runtime.KeepAlive(kem)
 
 	if rv != C.OQS_SUCCESS {
-		return nil, nil, errors.New("can not encapsulate secret")
+		return nil, nil, newStatusError(kem.algDetails.Name, "encaps", int(rv))
 	}
 
 	return ciphertext, sharedSecret, nil
@@ -228,37 +597,96 @@ func (kem *KeyEncapsulation) EncapSecret(publicKey []byte) (ciphertext,
 // DecapSecret decapsulates a ciphertexts and returns the corresponding shared
 // secret.
 func (kem *KeyEncapsulation) DecapSecret(ciphertext []byte) ([]byte, error) {
+	if kem.kem == nil {
+		return nil, newError("", "decaps", ErrNotInitialized)
+	}
+	sharedSecret := make([]byte, kem.algDetails.LengthSharedSecret)
+	if err := kem.decapSecret(ciphertext, sharedSecret); err != nil {
+		return nil, err
+	}
+	return sharedSecret, nil
+}
+
+// DecapSecretBuffer is like DecapSecret, but returns the shared secret in a
+// SecretBuffer, which the caller must destroy once done.
+func (kem *KeyEncapsulation) DecapSecretBuffer(ciphertext []byte) (
+	*SecretBuffer, error,
+) {
+	if kem.kem == nil {
+		return nil, newError("", "decaps", ErrNotInitialized)
+	}
+	sharedSecret, err := NewSecretBuffer(kem.algDetails.LengthSharedSecret)
+	if err != nil {
+		return nil, newError(kem.algDetails.Name, "decaps", err)
+	}
+	if err := kem.decapSecret(ciphertext, sharedSecret.Bytes()); err != nil {
+		sharedSecret.Destroy()
+		return nil, err
+	}
+	return sharedSecret, nil
+}
+
+// decapSecret decapsulates a ciphertext and writes the corresponding shared
+// secret into sharedSecret.
+func (kem *KeyEncapsulation) decapSecret(ciphertext,
+	sharedSecret []byte,
+) error {
 	if len(ciphertext) != kem.algDetails.LengthCiphertext {
-		return nil, errors.New("incorrect ciphertext length")
+		return newError(kem.algDetails.Name, "decaps",
+			ErrInvalidCiphertextLength)
 	}
 
-	if len(kem.secretKey) != kem.algDetails.LengthSecretKey {
-		return nil, errors.New("incorrect secret key length, make sure you " +
-			"specify one in Init() or run GenerateKeyPair()")
+	if err := kem.checkSecretKey("decaps"); err != nil {
+		return err
+	}
+	if kem.strict {
+		if err := kem.ValidateSecretKey(); err != nil {
+			return err
+		}
 	}
 
-	sharedSecret := make([]byte, kem.algDetails.LengthSharedSecret)
 	rv := C.OQS_KEM_decaps(
 		kem.kem,
-		(*C.uint8_t)(unsafe.Pointer(&sharedSecret[0])),
-		(*C.uchar)(unsafe.Pointer(&ciphertext[0])),
-		(*C.uint8_t)(unsafe.Pointer(&kem.secretKey[0])),
+		bytesPtr(sharedSecret),
+		bytesPtr(ciphertext),
+		bytesPtr(kem.secretKey),
 	)
+	runtime.KeepAlive(kem)
 
 	if rv != C.OQS_SUCCESS {
-		return nil, errors.New("can not decapsulate secret")
+		return newStatusError(kem.algDetails.Name, "decaps", int(rv))
 	}
 
-	return sharedSecret, nil
+	return nil
+}
+
+// checkSecretKey verifies that the kem receiver holds a secret key of the
+// correct length, and returns a typed error for the operation op otherwise.
+func (kem *KeyEncapsulation) checkSecretKey(op string) error {
+	if len(kem.secretKey) == 0 {
+		return newError(kem.algDetails.Name, op, ErrNoSecretKey)
+	}
+	if len(kem.secretKey) != kem.algDetails.LengthSecretKey {
+		return newError(kem.algDetails.Name, op, ErrInvalidSecretKeyLength)
+	}
+	return nil
 }
 
 // Clean zeroes-in the stored secret key and resets the kem receiver. One can
 // reuse the KEM by re-initializing it with the KeyEncapsulation.Init method.
+// Clean is idempotent, hence it is safe to invoke it more than once. A secret
+// key imported with WithBorrowedSecretKey is left untouched.
 func (kem *KeyEncapsulation) Clean() {
-	if len(kem.secretKey) > 0 {
-		MemCleanse(kem.secretKey)
+	if kem.finalizer {
+		runtime.SetFinalizer(kem, nil)
+	}
+	kem.secretBuf.Destroy()
+	if kem.kem != nil {
+		C.OQS_KEM_free(kem.kem)
+	}
+	if kem.release != nil {
+		kem.release()
 	}
-	C.OQS_KEM_free(kem.kem)
 	*kem = KeyEncapsulation{}
 }
 
@@ -280,7 +708,9 @@ func MaxNumberSigs() int {
 // IsSigEnabled returns true if a signature algorithm is enabled, and false
 // otherwise.
 func IsSigEnabled(algName string) bool {
-	result := C.OQS_SIG_alg_is_enabled(C.CString(algName))
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	result := C.OQS_SIG_alg_is_enabled(cAlgName)
 	return result != 0
 }
 
@@ -299,7 +729,7 @@ func IsSigSupported(algName string) bool {
 // numerical ID.
 func SigName(algID int) (string, error) {
 	if algID >= MaxNumberSigs() {
-		return "", errors.New("algorithm ID out of range")
+		return "", newError("", "name", ErrAlgorithmIDOutOfRange)
 	}
 	return C.GoString(C.OQS_SIG_alg_identifier(C.size_t(algID))), nil
 }
@@ -339,6 +769,8 @@ type SignatureDetails struct {
 	LengthPublicKey    int
 	LengthSecretKey    int
 	MaxLengthSignature int
+	LengthKeypairSeed  int  // 0 if deterministic key generation is unsupported
+	SecretKeyLocked    bool // whether the secret key is locked into RAM
 }
 
 // String converts the signature algorithm details to a string representation.
@@ -352,7 +784,9 @@ func (sigDetails SignatureDetails) String() string {
 		"Supports context string: %v\n"+
 		"Length public key (bytes): %d\n"+
 		"Length secret key (bytes): %d\n"+
-		"Maximum length signature (bytes): %d",
+		"Maximum length signature (bytes): %d\n"+
+		"Length keypair seed (bytes): %d\n"+
+		"Secret key locked: %v",
 		sigDetails.Name,
 		sigDetails.Version,
 		sigDetails.ClaimedNISTLevel,
@@ -360,14 +794,21 @@ func (sigDetails SignatureDetails) String() string {
 		sigDetails.SigWithCtxSupport,
 		sigDetails.LengthPublicKey,
 		sigDetails.LengthSecretKey,
-		sigDetails.MaxLengthSignature)
+		sigDetails.MaxLengthSignature,
+		sigDetails.LengthKeypairSeed,
+		sigDetails.SecretKeyLocked)
 }
 
 // Signature defines the signature main data structure.
 type Signature struct {
 	sig        *C.OQS_SIG
+	secretBuf  *SecretBuffer // holds secretKey and seed
 	secretKey  []byte
+	seed       []byte    // set by GenerateKeyPairFromSeed
+	rand       io.Reader // set by WithRand
 	algDetails SignatureDetails
+	release    func() // set by SigPool.Get, releases the place in the pool
+	finalizer  bool   // true if a finalizer was set by NewSignature
 }
 
 // String converts the signature algorithm name to a string representation.
@@ -378,23 +819,63 @@ func (sig Signature) String() string {
 		sig.algDetails.Name)
 }
 
+// NewSignature allocates and initializes a signature with an algorithm name,
+// configured by opts (e.g. WithSecretKey). The caller should invoke
+// Signature.Clean once done; a finalizer frees the underlying liboqs object
+// and cleanses the secret key if the caller forgets to.
+func NewSignature(algName string, opts ...Option) (*Signature, error) {
+	o := newOptions(opts)
+	sig := new(Signature)
+	if err := sig.Init(algName, o.ownedSecretKey()); err != nil {
+		return nil, err
+	}
+	if o.borrowed {
+		// a borrowed secret key is used in place, hence checked up front
+		if len(o.secretKey) != sig.algDetails.LengthSecretKey {
+			sig.Clean()
+			return nil, newError(sig.algDetails.Name, "init",
+				ErrInvalidSecretKeyLength)
+		}
+		sig.secretKey = o.secretKey
+	}
+	sig.rand = o.rand
+	sig.finalizer = true
+	runtime.SetFinalizer(sig, (*Signature).Clean)
+	return sig, nil
+}
+
 // Init initializes the signature data structure with an algorithm name and a
 // secret key. If the secret key is null, then the user must invoke the
 // Signature.GenerateKeyPair method to generate the pair of secret key/public
-// key.
+// key. The secret key is copied into a SecretBuffer, hence the caller may
+// cleanse its own copy. Init may be invoked again on an initialized receiver,
+// which releases the previous liboqs object.
 func (sig *Signature) Init(algName string, secretKey []byte) error {
 	if !IsSigEnabled(algName) {
 		// perhaps it's supported
 		if IsSigSupported(algName) {
-			return errors.New(`"` + algName +
-				`" signature mechanism is not enabled by OQS`)
+			return newError(algName, "init", ErrAlgorithmNotEnabled)
 		}
-		return errors.New(`"` + algName +
-			`" signature mechanism is not supported by OQS`)
-
+		return newError(algName, "init", ErrAlgorithmNotSupported)
+	}
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	handle := C.OQS_SIG_new(cAlgName)
+	if handle == nil {
+		return newError(algName, "init", ErrLiboqsFailure)
+	}
+	var err error
+	sig.secretBuf, sig.secretKey, sig.seed, err = replaceSecretKey(
+		sig.secretBuf, secretKey, len(secretKey), nil)
+	if err != nil {
+		C.OQS_SIG_free(handle)
+		return newError(algName, "init", err)
 	}
-	sig.sig = C.OQS_SIG_new(C.CString(algName))
-	sig.secretKey = secretKey
+	// re-initialization releases the previous liboqs object
+	if sig.sig != nil {
+		C.OQS_SIG_free(sig.sig)
+	}
+	sig.sig = handle
 	sig.algDetails.Name = C.GoString(sig.sig.method_name)
 	sig.algDetails.Version = C.GoString(sig.sig.alg_version)
 	sig.algDetails.ClaimedNISTLevel = int(sig.sig.claimed_nist_level)
@@ -403,13 +884,25 @@ func (sig *Signature) Init(algName string, secretKey []byte) error {
 	sig.algDetails.LengthPublicKey = int(sig.sig.length_public_key)
 	sig.algDetails.LengthSecretKey = int(sig.sig.length_secret_key)
 	sig.algDetails.MaxLengthSignature = int(sig.sig.length_signature)
+	// liboqs has no derandomized signature key generation API; the seed is
+	// fed through OQS_randombytes to the algorithms that draw exactly one
+	// seed during key generation, i.e. ML-DSA (FIPS 204)
+	sig.algDetails.LengthKeypairSeed = 0
+	if entry, ok := lookupAlgorithmName(sig.algDetails.Name); ok &&
+		!entry.isKEM {
+		sig.algDetails.LengthKeypairSeed = entry.seedLength
+	}
 
 	return nil
 }
 
-// Details returns the signature algorithm details.
+// Details returns the signature algorithm details. SecretKeyLocked reports
+// whether the secret key, if any, is locked into RAM, see
+// SecretBuffer.Locked; it is false for a borrowed secret key.
 func (sig *Signature) Details() SignatureDetails {
-	return sig.algDetails
+	details := sig.algDetails
+	details.SecretKeyLocked = sig.secretBuf.Locked()
+	return details
 }
 
 // GenerateKeyPair generates a pair of secret key/public key and returns the
@@ -417,47 +910,143 @@ func (sig *Signature) Details() SignatureDetails {
 // is not directly accessible, unless one exports it with
 // Signature.ExportSecretKey method.
 func (sig *Signature) GenerateKeyPair() ([]byte, error) {
+	if sig.sig == nil {
+		return nil, newError("", "keypair", ErrNotInitialized)
+	}
+
 	publicKey := make([]byte, sig.algDetails.LengthPublicKey)
-	sig.secretKey = make([]byte, sig.algDetails.LengthSecretKey)
+	var err error
+	sig.secretBuf, sig.secretKey, sig.seed, err = replaceSecretKey(
+		sig.secretBuf, nil, sig.algDetails.LengthSecretKey, nil)
+	if err != nil {
+		return nil, newError(sig.algDetails.Name, "keypair", err)
+	}
 
-	rv := C.OQS_SIG_keypair(
+	var rv C.OQS_STATUS
+	randErr := withRand(sig.rand, func() {
+		rv = C.OQS_SIG_keypair(
+			sig.sig,
+			bytesPtr(publicKey),
+			bytesPtr(sig.secretKey),
+		)
+	})
+	runtime.KeepAlive(sig)
+
+	if randErr != nil {
+		sig.secretBuf.Destroy()
+		sig.secretBuf, sig.secretKey = nil, nil
+		return nil, newError(sig.algDetails.Name, "keypair", randErr)
+	}
+	if rv != C.OQS_SUCCESS {
+		return nil, newStatusError(sig.algDetails.Name, "keypair", int(rv))
+	}
+
+	return publicKey, nil
+}
+
+// GenerateKeyPairFromSeed deterministically generates a pair of secret
+// key/public key from a seed of Details().LengthKeypairSeed bytes, e.g. the
+// 32-byte seed ξ of ML-DSA (FIPS 204), and returns the public key. The same
+// seed always yields the same key pair, hence the seed may be stored instead
+// of the expanded secret key. A copy of the seed is kept inside the sig
+// receiver, so that the secret key can be encoded in the PKCS#8 seed format.
+func (sig *Signature) GenerateKeyPairFromSeed(seed []byte) ([]byte, error) {
+	if sig.sig == nil {
+		return nil, newError("", "keypair", ErrNotInitialized)
+	}
+
+	if sig.algDetails.LengthKeypairSeed == 0 {
+		return nil, newError(sig.algDetails.Name, "keypair",
+			ErrDerandNotSupported)
+	}
+	if len(seed) != sig.algDetails.LengthKeypairSeed {
+		return nil, newError(sig.algDetails.Name, "keypair",
+			ErrInvalidSeedLength)
+	}
+
+	publicKey := make([]byte, sig.algDetails.LengthPublicKey)
+	var err error
+	sig.secretBuf, sig.secretKey, sig.seed, err = replaceSecretKey(
+		sig.secretBuf, nil, sig.algDetails.LengthSecretKey, seed)
+	if err != nil {
+		return nil, newError(sig.algDetails.Name, "keypair", err)
+	}
+
+	rv := C.sigKeypairFromSeed_cgo(
 		sig.sig,
-		(*C.uint8_t)(unsafe.Pointer(&publicKey[0])),
-		(*C.uint8_t)(unsafe.Pointer(&sig.secretKey[0])),
+		bytesPtr(publicKey),
+		bytesPtr(sig.secretKey),
+		bytesPtr(sig.seed),
+		C.size_t(len(sig.seed)),
 	)
+	runtime.KeepAlive(sig)
 
 	if rv != C.OQS_SUCCESS {
-		return nil, errors.New("can not generate keypair")
+		return nil, newStatusError(sig.algDetails.Name, "keypair", int(rv))
 	}
 
 	return publicKey, nil
 }
 
-// ExportSecretKey exports the corresponding secret key from the sig receiver.
+// ExportSecretKey exports a copy of the secret key of the sig receiver. The
+// secret key itself is kept in a SecretBuffer, hence the copy lives on the Go
+// heap; the caller should cleanse it with MemCleanse once done.
 func (sig *Signature) ExportSecretKey() []byte {
-	return sig.secretKey
+	if sig.secretKey == nil {
+		return nil
+	}
+	return append([]byte(nil), sig.secretKey...)
+}
+
+// ExportSecretKeyTo copies the secret key of the sig receiver into dst, e.g. a
+// SecretBuffer, without an intermediate copy on the Go heap, and returns the
+// number of bytes copied. It returns an error wrapping io.ErrShortBuffer if
+// dst is too short.
+func (sig *Signature) ExportSecretKeyTo(dst []byte) (int, error) {
+	if sig.sig == nil {
+		return 0, newError("", "export secret key", ErrNotInitialized)
+	}
+	if len(sig.secretKey) == 0 {
+		return 0, newError(sig.algDetails.Name, "export secret key",
+			ErrNoSecretKey)
+	}
+	if len(dst) < len(sig.secretKey) {
+		return 0, newError(sig.algDetails.Name, "export secret key",
+			io.ErrShortBuffer)
+	}
+	return copy(dst, sig.secretKey), nil
 }
 
 // Sign signs a message and returns the corresponding signature.
 func (sig *Signature) Sign(message []byte) ([]byte, error) {
-	if len(sig.secretKey) != sig.algDetails.LengthSecretKey {
-		return nil, errors.New("incorrect secret key length, make sure you " +
-			"specify one in Init() or run GenerateKeyPair()")
+	if sig.sig == nil {
+		return nil, newError("", "sign", ErrNotInitialized)
+	}
+
+	if err := sig.checkSecretKey("sign"); err != nil {
+		return nil, err
 	}
 
 	signature := make([]byte, sig.algDetails.MaxLengthSignature)
 	var lenSig uint64
-	rv := C.OQS_SIG_sign(
-		sig.sig,
-		(*C.uint8_t)(unsafe.Pointer(&signature[0])),
-		(*C.size_t)(unsafe.Pointer(&lenSig)),
-		(*C.uint8_t)(unsafe.Pointer(&message[0])),
-		C.size_t(len(message)),
-		(*C.uint8_t)(unsafe.Pointer(&sig.secretKey[0])),
-	)
-
+	var rv C.OQS_STATUS
+	randErr := withRand(sig.rand, func() {
+		rv = C.OQS_SIG_sign(
+			sig.sig,
+			bytesPtr(signature),
+			(*C.size_t)(unsafe.Pointer(&lenSig)),
+			bytesPtr(message),
+			C.size_t(len(message)),
+			bytesPtr(sig.secretKey),
+		)
+	})
+	runtime.KeepAlive(sig)
+
+	if randErr != nil {
+		return nil, newError(sig.algDetails.Name, "sign", randErr)
+	}
 	if rv != C.OQS_SUCCESS {
-		return nil, errors.New("can not sign message")
+		return nil, newStatusError(sig.algDetails.Name, "sign", int(rv))
 	}
 
 	return signature[:lenSig], nil
@@ -466,30 +1055,41 @@ func (sig *Signature) Sign(message []byte) ([]byte, error) {
 // Sign signs a message with context string and returns the corresponding
 // signature.
 func (sig *Signature) SignWithCtxStr(message []byte, context []byte) ([]byte, error) {
+	if sig.sig == nil {
+		return nil, newError("", "sign", ErrNotInitialized)
+	}
+
 	if len(context) > 0 && !sig.algDetails.SigWithCtxSupport {
-		return nil, errors.New("can not sign message with context string")
+		return nil, newError(sig.algDetails.Name, "sign",
+			ErrContextNotSupported)
 	}
 
-	if len(sig.secretKey) != sig.algDetails.LengthSecretKey {
-		return nil, errors.New("incorrect secret key length, make sure you " +
-			"specify one in Init() or run GenerateKeyPair()")
+	if err := sig.checkSecretKey("sign"); err != nil {
+		return nil, err
 	}
 
 	signature := make([]byte, sig.algDetails.MaxLengthSignature)
 	var lenSig uint64
-	rv := C.OQS_SIG_sign_with_ctx_str(
-		sig.sig,
-		(*C.uint8_t)(unsafe.Pointer(&signature[0])),
-		(*C.size_t)(unsafe.Pointer(&lenSig)),
-		(*C.uint8_t)(unsafe.Pointer(&message[0])),
-		C.size_t(len(message)),
-		(*C.uint8_t)(unsafe.Pointer(&context[0])),
-		C.size_t(len(context)),
-		(*C.uint8_t)(unsafe.Pointer(&sig.secretKey[0])),
-	)
-
+	var rv C.OQS_STATUS
+	randErr := withRand(sig.rand, func() {
+		rv = C.OQS_SIG_sign_with_ctx_str(
+			sig.sig,
+			bytesPtr(signature),
+			(*C.size_t)(unsafe.Pointer(&lenSig)),
+			bytesPtr(message),
+			C.size_t(len(message)),
+			bytesPtr(context),
+			C.size_t(len(context)),
+			bytesPtr(sig.secretKey),
+		)
+	})
+	runtime.KeepAlive(sig)
+
+	if randErr != nil {
+		return nil, newError(sig.algDetails.Name, "sign", randErr)
+	}
 	if rv != C.OQS_SUCCESS {
-		return nil, errors.New("can not sign message")
+		return nil, newStatusError(sig.algDetails.Name, "sign", int(rv))
 	}
 
 	return signature[:lenSig], nil
@@ -500,22 +1100,23 @@ func (sig *Signature) SignWithCtxStr(message []byte, context []byte) ([]byte, er
 func (sig *Signature) Verify(message []byte, signature []byte,
 	publicKey []byte,
 ) (bool, error) {
-	if len(publicKey) != sig.algDetails.LengthPublicKey {
-		return false, errors.New("incorrect public key length")
+	if sig.sig == nil {
+		return false, newError("", "verify", ErrNotInitialized)
 	}
 
-	if len(signature) > sig.algDetails.MaxLengthSignature {
-		return false, errors.New("incorrect signature size")
+	if err := sig.checkVerifyArgs(signature, publicKey); err != nil {
+		return false, err
 	}
 
 	rv := C.OQS_SIG_verify(
 		sig.sig,
-		(*C.uint8_t)(unsafe.Pointer(&message[0])),
+		bytesPtr(message),
 		C.size_t(len(message)),
-		(*C.uint8_t)(unsafe.Pointer(&signature[0])),
+		bytesPtr(signature),
 		C.size_t(len(signature)),
-		(*C.uint8_t)(unsafe.Pointer(&publicKey[0])),
+		bytesPtr(publicKey),
 	)
+	runtime.KeepAlive(sig)
 
 	if rv != C.OQS_SUCCESS {
 		return false, nil
@@ -532,30 +1133,30 @@ func (sig *Signature) VerifyWithCtxStr(
 	context []byte,
 	publicKey []byte,
 ) (bool, error) {
-	if len(context) > 0 && !sig.algDetails.SigWithCtxSupport {
-		return false, errors.New("can not sign message with context string")
+	if sig.sig == nil {
+		return false, newError("", "verify", ErrNotInitialized)
 	}
 
-	if len(publicKey) != sig.algDetails.LengthPublicKey {
-		return false, errors.New("incorrect public key length")
+	if len(context) > 0 && !sig.algDetails.SigWithCtxSupport {
+		return false, newError(sig.algDetails.Name, "verify",
+			ErrContextNotSupported)
 	}
 
-	if len(signature) > sig.algDetails.MaxLengthSignature {
-		return false, errors.New("incorrect signature size")
+	if err := sig.checkVerifyArgs(signature, publicKey); err != nil {
+		return false, err
 	}
 
 	rv := C.OQS_SIG_verify_with_ctx_str(
 		sig.sig,
-		(*C.uint8_t)(
-			unsafe.Pointer(&message[0]),
-		),
+		bytesPtr(message),
 		C.size_t(len(message)),
-		(*C.uint8_t)(unsafe.Pointer(&signature[0])),
+		bytesPtr(signature),
 		C.size_t(len(signature)),
-		(*C.uint8_t)(unsafe.Pointer(&context[0])),
+		bytesPtr(context),
 		C.size_t(len(context)),
-		(*C.uint8_t)(unsafe.Pointer(&publicKey[0])),
+		bytesPtr(publicKey),
 	)
+	runtime.KeepAlive(sig)
 
 	if rv != C.OQS_SUCCESS {
 		return false, nil
@@ -564,13 +1165,47 @@ func (sig *Signature) VerifyWithCtxStr(
 	return true, nil
 }
 
+// checkSecretKey verifies that the sig receiver holds a secret key of the
+// correct length, and returns a typed error for the operation op otherwise.
+func (sig *Signature) checkSecretKey(op string) error {
+	if len(sig.secretKey) == 0 {
+		return newError(sig.algDetails.Name, op, ErrNoSecretKey)
+	}
+	if len(sig.secretKey) != sig.algDetails.LengthSecretKey {
+		return newError(sig.algDetails.Name, op, ErrInvalidSecretKeyLength)
+	}
+	return nil
+}
+
+// checkVerifyArgs validates the lengths of the signature and public key
+// passed to the Verify family of methods.
+func (sig *Signature) checkVerifyArgs(signature, publicKey []byte) error {
+	if len(publicKey) != sig.algDetails.LengthPublicKey {
+		return newError(sig.algDetails.Name, "verify",
+			ErrInvalidPublicKeyLength)
+	}
+	if len(signature) > sig.algDetails.MaxLengthSignature {
+		return newError(sig.algDetails.Name, "verify",
+			ErrInvalidSignatureLength)
+	}
+	return nil
+}
+
 // Clean zeroes-in the stored secret key and resets the sig receiver. One can
 // reuse the signature by re-initializing it with the Signature.Init method.
+// Clean is idempotent, hence it is safe to invoke it more than once. A secret
+// key imported with WithBorrowedSecretKey is left untouched.
 func (sig *Signature) Clean() {
-	if len(sig.secretKey) > 0 {
-		MemCleanse(sig.secretKey)
+	if sig.finalizer {
+		runtime.SetFinalizer(sig, nil)
+	}
+	sig.secretBuf.Destroy()
+	if sig.sig != nil {
+		C.OQS_SIG_free(sig.sig)
+	}
+	if sig.release != nil {
+		sig.release()
 	}
-	C.OQS_SIG_free(sig.sig)
 	*sig = Signature{}
 }
 
@@ -581,25 +1216,107 @@ func (sig *Signature) Clean() {
 /**************** Callbacks ****************/
 
 // randAlgorithmPtrCallback is a global RNG algorithm callback set by
+// RandomBytesCustomAlgorithm. randCallbackMutex guards it, and serializes its
+// invocations, so that the callback need not be safe for concurrent use.
+var (
+	randAlgorithmPtrCallback func([]byte, int)
+	randCallbackMutex        sync.Mutex
+)
+
+// randMutex serializes RandomBytesSwitchAlgorithm and
 // RandomBytesCustomAlgorithm.
-var randAlgorithmPtrCallback func([]byte, int)
+var randMutex sync.Mutex
 
 // randAlgorithmPtr is automatically invoked by RandomBytesCustomAlgorithm. When
 // invoked, the memory is provided by the caller, i.e. RandomBytes or
-// RandomBytesInPlace.
+// RandomBytesInPlace. The callback writes straight into that memory through a
+// slice view, so no Go buffer is allocated nor copied.
 //
 //export randAlgorithmPtr
 func randAlgorithmPtr(randomArray *C.uint8_t, bytesToRead C.size_t) {
-	// TODO optimize the copying if possible!
-	result := make([]byte, int(bytesToRead))
+	result := unsafe.Slice((*byte)(unsafe.Pointer(randomArray)),
+		int(bytesToRead))
+	randCallbackMutex.Lock()
+	defer randCallbackMutex.Unlock()
+	if randAlgorithmPtrCallback == nil {
+		// only reachable while RandomBytesSwitchAlgorithm concurrently
+		// replaces the custom algorithm
+		C.OQS_randombytes_system(randomArray, bytesToRead)
+		return
+	}
 	randAlgorithmPtrCallback(result, int(bytesToRead))
-	p := unsafe.Pointer(randomArray)
-	for _, v := range result {
-		*(*C.uint8_t)(p) = C.uint8_t(v)
-		p = unsafe.Pointer(uintptr(p) + 1)
+}
+
+// readerRandContext is the state of an operation whose randomness is drawn
+// from an io.Reader, see WithRand.
+type readerRandContext struct {
+	r   io.Reader
+	err error // first error returned by r
+}
+
+// readerRandAlgorithm is invoked by threadRandAlgorithm_cgo on behalf of the
+// thread that runs withRand. Once the reader fails, the remaining requests
+// are zero-filled, and the result of the operation is discarded by withRand.
+//
+//export readerRandAlgorithm
+func readerRandAlgorithm(handle C.uintptr_t, randomArray *C.uint8_t,
+	bytesToRead C.size_t,
+) {
+	ctx := cgo.Handle(handle).Value().(*readerRandContext)
+	buf := unsafe.Slice((*byte)(unsafe.Pointer(randomArray)), int(bytesToRead))
+	if ctx.err == nil {
+		_, ctx.err = io.ReadFull(ctx.r, buf)
+	}
+	if ctx.err != nil {
+		clear(buf)
 	}
 }
 
+// readerMutex serializes the operations that draw from a WithRand reader, so
+// that readers need not be safe for concurrent use.
+var readerMutex sync.Mutex
+
+// serializedReader reads from a WithRand reader outside of liboqs, under
+// readerMutex.
+type serializedReader struct {
+	r io.Reader
+}
+
+// Read implements io.Reader.
+func (s serializedReader) Read(b []byte) (int, error) {
+	readerMutex.Lock()
+	defer readerMutex.Unlock()
+	return s.r.Read(b)
+}
+
+// withRand runs f, which invokes liboqs, with the random bytes requested by
+// the calling thread drawn from r; the other threads are unaffected. If r is
+// nil, f simply uses the selected RNG algorithm. The returned error wraps
+// ErrRandFailure if r failed.
+func withRand(r io.Reader, f func()) error {
+	if r == nil {
+		f()
+		return nil
+	}
+	readerMutex.Lock()
+	defer readerMutex.Unlock()
+	ctx := &readerRandContext{r: r}
+	handle := cgo.NewHandle(ctx)
+	defer handle.Delete()
+	func() {
+		// the thread-local state must be set on the thread that runs f
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		C.setReaderRand_cgo(C.uintptr_t(handle))
+		defer C.setReaderRand_cgo(0)
+		f()
+	}()
+	if ctx.err != nil {
+		return fmt.Errorf("%w: %w", ErrRandFailure, ctx.err)
+	}
+	return nil
+}
+
 /**************** END Callbacks ****************/
 
 // RandomBytes generates bytesToRead random bytes. This implementation uses
@@ -607,8 +1324,9 @@ func randAlgorithmPtr(randomArray *C.uint8_t, bytesToRead C.size_t) {
 // selected by RandomBytesSwitchAlgorithm.
 func RandomBytes(bytesToRead int) []byte {
 	result := make([]byte, bytesToRead)
-	C.OQS_randombytes((*C.uint8_t)(unsafe.Pointer(&result[0])),
-		C.size_t(bytesToRead))
+	if bytesToRead > 0 {
+		C.OQS_randombytes(bytesPtr(result), C.size_t(bytesToRead))
+	}
 	return result
 }
 
@@ -620,32 +1338,77 @@ func RandomBytesInPlace(randomArray []byte, bytesToRead int) {
 	if bytesToRead > len(randomArray) {
 		bytesToRead = len(randomArray)
 	}
-	C.OQS_randombytes((*C.uint8_t)(unsafe.Pointer(&randomArray[0])),
-		C.size_t(bytesToRead))
+	if bytesToRead <= 0 {
+		return
+	}
+	C.OQS_randombytes(bytesPtr(randomArray), C.size_t(bytesToRead))
 }
 
 // RandomBytesSwitchAlgorithm switches the core OQS_randombytes to use the
 // specified algorithm. Possible values are "system" and "OpenSSL".
 // See <oqs/rand.h> liboqs header for more details.
 func RandomBytesSwitchAlgorithm(algName string) error {
-	if C.OQS_randombytes_switch_algorithm(C.CString(algName)) != C.OQS_SUCCESS {
-		return errors.New("can not switch to \"" + algName + "\" algorithm")
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	rv := C.selectRandAlgorithm_cgo(cAlgName)
+	if rv != C.OQS_SUCCESS {
+		return newStatusError(algName, "switch RNG", int(rv))
 	}
+	randCallbackMutex.Lock()
+	randAlgorithmPtrCallback = nil
+	randCallbackMutex.Unlock()
 	return nil
 }
 
 // RandomBytesCustomAlgorithm switches RandomBytes to use the given function.
 // This allows additional custom RNGs besides the provided ones. The provided
 // RNG function must have the same signature as RandomBytesInPlace,
-// i.e. func([]byte, int).
+// i.e. func([]byte, int). The slice passed to fun may refer to C memory,
+// hence fun must not retain it. The RNG algorithm is process-wide; its
+// invocations are serialized, hence fun must not invoke RandomBytes itself.
+// Use WithRand to confine a custom RNG to a single KeyEncapsulation or
+// Signature.
 func RandomBytesCustomAlgorithm(fun func([]byte, int)) error {
 	if fun == nil {
-		return errors.New("the RNG algorithm callback can not be nil")
+		return newError("", "custom RNG", ErrNilCallback)
 	}
+	randMutex.Lock()
+	defer randMutex.Unlock()
+	randCallbackMutex.Lock()
+	previous := randAlgorithmPtrCallback
 	randAlgorithmPtrCallback = fun
-	C.OQS_randombytes_custom_algorithm(
-		(C.rand_algorithm_ptr)(unsafe.Pointer(C.randAlgorithmPtr_cgo)))
+	randCallbackMutex.Unlock()
+	if rv := C.selectRandAlgorithm_cgo(nil); rv != C.OQS_SUCCESS {
+		randCallbackMutex.Lock()
+		randAlgorithmPtrCallback = previous
+		randCallbackMutex.Unlock()
+		return newStatusError("", "custom RNG", int(rv))
+	}
 	return nil
 }
 
+// Rand is a global, shared instance of a cryptographically secure random
+// number generator backed by OQS_randombytes, hence by whichever algorithm has
+// been selected by RandomBytesSwitchAlgorithm or RandomBytesCustomAlgorithm.
+// It can be used wherever crypto/rand.Reader is accepted. Note that since Go
+// 1.26 the standard library ignores custom readers in key generation unless
+// GODEBUG=cryptocustomrand=1 is set, which is the default for modules that
+// declare an older Go version.
+var Rand io.Reader = randReader{}
+
+// randReader implements io.Reader on top of OQS_randombytes.
+type randReader struct{}
+
+// Read fills b with random bytes, directly through OQS_randombytes. It never
+// fails, and is a no-op if b is empty.
+func (randReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	C.OQS_randombytes(bytesPtr(b), C.size_t(len(b)))
+	return len(b), nil
+}
+
 /**************** END Randomness ****************/
@@ -0,0 +1,454 @@
+package oqs
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+)
+
+/**************** PKIX/PKCS#8 encoding ****************/
+
+// OIDs of the ML-DSA parameter sets (FIPS 204), as assigned by NIST.
+var (
+	oidMLDSA44 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 17}
+	oidMLDSA65 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18}
+	oidMLDSA87 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 19}
+)
+
+// algorithmOID maps a liboqs algorithm name to its OID. If seedLength is not
+// zero, the private key is encoded as the seed/expandedKey/both CHOICE of the
+// LAMPS ML-KEM and ML-DSA drafts; otherwise, it is encoded as is.
+type algorithmOID struct {
+	name       string
+	oid        asn1.ObjectIdentifier
+	isKEM      bool
+	seedLength int
+}
+
+// algorithmOIDs lists the OIDs assigned by NIST to ML-KEM, ML-DSA and
+// SLH-DSA, and the experimental OIDs used by the OQS provider for Falcon.
+var algorithmOIDs = []algorithmOID{
+	{"ML-KEM-512", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 1}, true, 64},
+	{"ML-KEM-768", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}, true, 64},
+	{"ML-KEM-1024", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}, true, 64},
+	{"ML-DSA-44", oidMLDSA44, false, 32},
+	{"ML-DSA-65", oidMLDSA65, false, 32},
+	{"ML-DSA-87", oidMLDSA87, false, 32},
+	{"SLH_DSA_PURE_SHA2_128S", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 20}, false, 0},
+	{"SLH_DSA_PURE_SHA2_128F", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 21}, false, 0},
+	{"SLH_DSA_PURE_SHA2_192S", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 22}, false, 0},
+	{"SLH_DSA_PURE_SHA2_192F", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 23}, false, 0},
+	{"SLH_DSA_PURE_SHA2_256S", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 24}, false, 0},
+	{"SLH_DSA_PURE_SHA2_256F", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 25}, false, 0},
+	{"SLH_DSA_PURE_SHAKE_128S", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 26}, false, 0},
+	{"SLH_DSA_PURE_SHAKE_128F", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 27}, false, 0},
+	{"SLH_DSA_PURE_SHAKE_192S", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 28}, false, 0},
+	{"SLH_DSA_PURE_SHAKE_192F", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 29}, false, 0},
+	{"SLH_DSA_PURE_SHAKE_256S", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 30}, false, 0},
+	{"SLH_DSA_PURE_SHAKE_256F", asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 31}, false, 0},
+	{"Falcon-512", asn1.ObjectIdentifier{1, 3, 9999, 3, 11}, false, 0},
+	{"Falcon-1024", asn1.ObjectIdentifier{1, 3, 9999, 3, 14}, false, 0},
+}
+
+// lookupAlgorithmName returns the OID entry of a liboqs algorithm name.
+func lookupAlgorithmName(algName string) (*algorithmOID, bool) {
+	for i := range algorithmOIDs {
+		if algorithmOIDs[i].name == algName {
+			return &algorithmOIDs[i], true
+		}
+	}
+	return nil, false
+}
+
+// lookupAlgorithmOID returns the OID entry of an OID.
+func lookupAlgorithmOID(oid asn1.ObjectIdentifier) (*algorithmOID, bool) {
+	for i := range algorithmOIDs {
+		if algorithmOIDs[i].oid.Equal(oid) {
+			return &algorithmOIDs[i], true
+		}
+	}
+	return nil, false
+}
+
+// lookupCompositeOID returns the name of a composite signature OID.
+func lookupCompositeOID(oid asn1.ObjectIdentifier) (string, bool) {
+	for name, params := range compositeSigs {
+		if params.oid.Equal(oid) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// AlgorithmOID returns the OID of a KEM, signature or composite signature
+// algorithm.
+func AlgorithmOID(algName string) (asn1.ObjectIdentifier, error) {
+	if entry, ok := lookupAlgorithmName(algName); ok {
+		return entry.oid, nil
+	}
+	if params, ok := compositeSigs[algName]; ok {
+		return params.oid, nil
+	}
+	return nil, newError(algName, "oid", ErrUnknownOID)
+}
+
+// AlgorithmName returns the liboqs (or composite) algorithm name of an OID.
+func AlgorithmName(oid asn1.ObjectIdentifier) (string, error) {
+	if entry, ok := lookupAlgorithmOID(oid); ok {
+		return entry.name, nil
+	}
+	if name, ok := lookupCompositeOID(oid); ok {
+		return name, nil
+	}
+	return "", newError(oid.String(), "oid", ErrUnknownOID)
+}
+
+// PrivateKeyFormat selects the encoding of ML-KEM and ML-DSA private keys
+// inside PKCS#8, see the LAMPS ML-KEM and ML-DSA certificate drafts. It is
+// ignored by the other algorithms, whose private keys are encoded as is.
+type PrivateKeyFormat int
+
+const (
+	// PrivateKeyExpanded encodes the expanded secret key.
+	PrivateKeyExpanded PrivateKeyFormat = iota
+	// PrivateKeySeed encodes the key generation seed only. It requires a key
+	// pair generated by GenerateKeyPairFromSeed.
+	PrivateKeySeed
+	// PrivateKeyBoth encodes both the seed and the expanded secret key. It
+	// requires a key pair generated by GenerateKeyPairFromSeed.
+	PrivateKeyBoth
+)
+
+// subjectPublicKeyInfo is the SubjectPublicKeyInfo structure of RFC 5280.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// oneAsymmetricKey is the OneAsymmetricKey (PKCS#8) structure of RFC 5958,
+// without the optional attributes and public key.
+type oneAsymmetricKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// privateKeyBoth is the "both" alternative of the ML-KEM and ML-DSA private
+// key CHOICE.
+type privateKeyBoth struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+// MarshalPKIXPublicKey converts a public key to the DER-encoded PKIX
+// SubjectPublicKeyInfo form. pub must be a *KEMPublicKey, a *SigPublicKey or
+// a *CompositePublicKey.
+func MarshalPKIXPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	var algName string
+	var publicKey []byte
+	switch k := pub.(type) {
+	case *KEMPublicKey:
+		algName, publicKey = k.algName, k.publicKey
+	case *SigPublicKey:
+		algName, publicKey = k.algName, k.publicKey
+	case *CompositePublicKey:
+		var err error
+		if publicKey, err = k.Bytes(); err != nil {
+			return nil, err
+		}
+		algName = k.algName
+	default:
+		return nil, newError("", "marshal public key", ErrUnsupportedKeyType)
+	}
+	oid, err := AlgorithmOID(algName)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{
+			Bytes:     publicKey,
+			BitLength: 8 * len(publicKey),
+		},
+	})
+}
+
+// ParsePKIXPublicKey parses a DER-encoded PKIX SubjectPublicKeyInfo. It
+// returns a *KEMPublicKey, a *SigPublicKey or a *CompositePublicKey, depending
+// on the algorithm OID.
+func ParsePKIXPublicKey(der []byte) (crypto.PublicKey, error) {
+	var spki subjectPublicKeyInfo
+	if rest, err := asn1.Unmarshal(der, &spki); err != nil || len(rest) != 0 ||
+		spki.PublicKey.BitLength%8 != 0 {
+		return nil, newError("", "parse public key", ErrInvalidPublicKey)
+	}
+	algName, err := AlgorithmName(spki.Algorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := compositeSigs[algName]; ok {
+		return ParseCompositePublicKey(algName, spki.PublicKey.Bytes)
+	}
+	if entry, _ := lookupAlgorithmName(algName); entry.isKEM {
+		return NewKEMPublicKey(algName, spki.PublicKey.Bytes)
+	}
+	return NewSigPublicKey(algName, spki.PublicKey.Bytes)
+}
+
+// MarshalPKCS8PrivateKey converts a private key to the DER-encoded PKCS#8
+// form, using the expanded form for ML-KEM and ML-DSA secret keys. key must
+// be a *KeyEncapsulation, a *KEMPrivateKey, a *Signature, a *SigPrivateKey or
+// a *CompositePrivateKey.
+func MarshalPKCS8PrivateKey(key any) ([]byte, error) {
+	return MarshalPKCS8PrivateKeyFormat(key, PrivateKeyExpanded)
+}
+
+// MarshalPKCS8PrivateKeyFormat is like MarshalPKCS8PrivateKey, but encodes
+// ML-KEM and ML-DSA secret keys in the given format. ErrNoSeed is returned if
+// the format includes the seed, but the key pair was not generated from one.
+func MarshalPKCS8PrivateKeyFormat(key any, format PrivateKeyFormat) ([]byte,
+	error,
+) {
+	var algName string
+	var secretKey, seed []byte
+	switch k := key.(type) {
+	case *KeyEncapsulation:
+		if err := k.checkSecretKey("marshal private key"); err != nil {
+			return nil, err
+		}
+		algName, secretKey, seed = k.algDetails.Name, k.secretKey, k.seed
+	case *Signature:
+		if err := k.checkSecretKey("marshal private key"); err != nil {
+			return nil, err
+		}
+		algName, secretKey, seed = k.algDetails.Name, k.secretKey, k.seed
+	case *KEMPrivateKey:
+		return MarshalPKCS8PrivateKeyFormat(k.kem, format)
+	case *SigPrivateKey:
+		return MarshalPKCS8PrivateKeyFormat(k.signer, format)
+	case *CompositePrivateKey:
+		der, err := k.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		return marshalOneAsymmetricKey(k.public.params.oid, der)
+	default:
+		return nil, newError("", "marshal private key", ErrUnsupportedKeyType)
+	}
+	entry, ok := lookupAlgorithmName(algName)
+	if !ok {
+		return nil, newError(algName, "marshal private key", ErrUnknownOID)
+	}
+	if entry.seedLength == 0 {
+		return marshalOneAsymmetricKey(entry.oid, secretKey)
+	}
+	var privateKey []byte
+	var err error
+	switch {
+	case format == PrivateKeyExpanded:
+		privateKey, err = asn1.Marshal(secretKey)
+	case seed == nil:
+		return nil, newError(algName, "marshal private key", ErrNoSeed)
+	case format == PrivateKeySeed:
+		privateKey, err = asn1.Marshal(asn1.RawValue{
+			Class: asn1.ClassContextSpecific,
+			Tag:   0,
+			Bytes: seed,
+		})
+	default:
+		privateKey, err = asn1.Marshal(privateKeyBoth{
+			Seed:        seed,
+			ExpandedKey: secretKey,
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return marshalOneAsymmetricKey(entry.oid, privateKey)
+}
+
+// marshalOneAsymmetricKey encodes a PKCS#8 private key.
+func marshalOneAsymmetricKey(oid asn1.ObjectIdentifier,
+	privateKey []byte,
+) ([]byte, error) {
+	return asn1.Marshal(oneAsymmetricKey{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey: privateKey,
+	})
+}
+
+// ParsePKCS8PrivateKey parses a DER-encoded PKCS#8 private key. It returns a
+// ready-to-use *KeyEncapsulation, *Signature or *CompositePrivateKey,
+// depending on the algorithm OID; the caller should Clean it once done.
+// ML-KEM and ML-DSA secret keys may be in any of the seed, expandedKey and
+// both forms; a seed is expanded with GenerateKeyPairFromSeed, and must match
+// the expanded key of the both form, whereas an ML-KEM expanded key alone
+// must pass KeyEncapsulation.ValidateSecretKey.
+func ParsePKCS8PrivateKey(der []byte) (any, error) {
+	var key oneAsymmetricKey
+	if rest, err := asn1.Unmarshal(der, &key); err != nil || len(rest) != 0 ||
+		(key.Version != 0 && key.Version != 1) {
+		return nil, newError("", "parse private key", ErrInvalidSecretKey)
+	}
+	algName, err := AlgorithmName(key.Algorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := compositeSigs[algName]; ok {
+		return ParseCompositePrivateKey(algName, key.PrivateKey)
+	}
+	entry, _ := lookupAlgorithmName(algName)
+	secretKey := key.PrivateKey
+	var seed []byte
+	if entry.seedLength != 0 {
+		seed, secretKey, err = parseSeedOrExpanded(entry, secretKey)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if seed != nil {
+		return parseSeed(entry, seed, secretKey)
+	}
+	if entry.isKEM {
+		kem, err := NewKeyEncapsulation(algName, WithSecretKey(secretKey))
+		if err != nil {
+			return nil, err
+		}
+		if err := kem.ValidateSecretKey(); err != nil {
+			kem.Clean()
+			return nil, err
+		}
+		return kem, nil
+	}
+	sig, err := NewSignature(algName, WithSecretKey(secretKey))
+	if err != nil {
+		return nil, err
+	}
+	if err := sig.checkSecretKey("parse private key"); err != nil {
+		sig.Clean()
+		return nil, err
+	}
+	return sig, nil
+}
+
+// parseSeedOrExpanded decodes the seed/expandedKey/both CHOICE of ML-KEM and
+// ML-DSA private keys, and returns the seed and the expanded secret key,
+// either of which may be nil.
+func parseSeedOrExpanded(entry *algorithmOID, der []byte) (seed,
+	expandedKey []byte, err error,
+) {
+	var raw asn1.RawValue
+	if rest, err := asn1.Unmarshal(der, &raw); err != nil || len(rest) != 0 {
+		return nil, nil, newError(entry.name, "parse private key",
+			ErrInvalidSecretKey)
+	}
+	switch {
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString:
+		return nil, raw.Bytes, nil
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence:
+		var both privateKeyBoth
+		if _, err := asn1.Unmarshal(der, &both); err != nil ||
+			len(both.Seed) != entry.seedLength {
+			return nil, nil, newError(entry.name, "parse private key",
+				ErrInvalidSecretKey)
+		}
+		return both.Seed, both.ExpandedKey, nil
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 &&
+		!raw.IsCompound:
+		if len(raw.Bytes) != entry.seedLength {
+			return nil, nil, newError(entry.name, "parse private key",
+				ErrInvalidSecretKey)
+		}
+		return raw.Bytes, nil, nil
+	}
+	return nil, nil, newError(entry.name, "parse private key",
+		ErrInvalidSecretKey)
+}
+
+// parseSeed regenerates a private key from its seed. If expandedKey is not
+// nil, i.e. the key was encoded in the "both" form, it must coincide with the
+// regenerated secret key.
+func parseSeed(entry *algorithmOID, seed, expandedKey []byte) (any, error) {
+	var key interface {
+		GenerateKeyPairFromSeed([]byte) ([]byte, error)
+		Clean()
+	}
+	var secretKey *[]byte
+	if entry.isKEM {
+		kem, err := NewKeyEncapsulation(entry.name)
+		if err != nil {
+			return nil, err
+		}
+		key, secretKey = kem, &kem.secretKey
+	} else {
+		sig, err := NewSignature(entry.name)
+		if err != nil {
+			return nil, err
+		}
+		key, secretKey = sig, &sig.secretKey
+	}
+	if _, err := key.GenerateKeyPairFromSeed(seed); err != nil {
+		key.Clean()
+		return nil, err
+	}
+	if expandedKey != nil &&
+		subtle.ConstantTimeCompare(expandedKey, *secretKey) != 1 {
+		key.Clean()
+		return nil, newError(entry.name, "parse private key",
+			ErrInvalidSecretKey)
+	}
+	return key, nil
+}
+
+// PEM block types used by the PEM helpers.
+const (
+	pemTypePublicKey  = "PUBLIC KEY"
+	pemTypePrivateKey = "PRIVATE KEY"
+)
+
+// EncodePublicKeyPEM encodes a public key as a PEM "PUBLIC KEY" block, see
+// MarshalPKIXPublicKey.
+func EncodePublicKeyPEM(pub crypto.PublicKey) ([]byte, error) {
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePublicKey, Bytes: der}),
+		nil
+}
+
+// DecodePublicKeyPEM decodes the first PEM "PUBLIC KEY" block of data, see
+// ParsePKIXPublicKey.
+func DecodePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemTypePublicKey {
+		return nil, newError("", "decode public key", ErrInvalidPEM)
+	}
+	return ParsePKIXPublicKey(block.Bytes)
+}
+
+// EncodePrivateKeyPEM encodes a private key as a PEM "PRIVATE KEY" block, see
+// MarshalPKCS8PrivateKey.
+func EncodePrivateKeyPEM(key any) ([]byte, error) {
+	der, err := MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	defer MemCleanse(der)
+	return pem.EncodeToMemory(&pem.Block{Type: pemTypePrivateKey, Bytes: der}),
+		nil
+}
+
+// DecodePrivateKeyPEM decodes the first PEM "PRIVATE KEY" block of data, see
+// ParsePKCS8PrivateKey.
+func DecodePrivateKeyPEM(data []byte) (any, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != pemTypePrivateKey {
+		return nil, newError("", "decode private key", ErrInvalidPEM)
+	}
+	return ParsePKCS8PrivateKey(block.Bytes)
+}
+
+/**************** END PKIX/PKCS#8 encoding ****************/
@@ -0,0 +1,279 @@
+package oqs
+
+/*
+#include <oqs/oqs.h>
+*/
+import "C"
+
+import (
+	"runtime"
+	"sync"
+)
+
+/**************** Pools ****************/
+
+// PoolStats reports the activity of a KEMPool or a SigPool.
+type PoolStats struct {
+	Gets  uint64 // handles handed out by Get
+	Hits  uint64 // handles handed out from the idle handles
+	Puts  uint64 // handles returned by Put
+	Drops uint64 // returned handles freed because the pool was full
+	Idle  int    // handles currently idle, for all algorithms
+	InUse int    // handles handed out and not yet returned
+}
+
+// handlePool keeps the idle liboqs objects of a KEMPool or a SigPool, keyed by
+// algorithm name. Only the liboqs objects are pooled: every Get wraps them in
+// a fresh Go value, and Put resets the returned Go value, so that any later
+// use of it fails with ErrNotInitialized instead of sharing the liboqs object
+// with its next user. The handles in use are bounded by the capacity of
+// inUse, if not nil, which Get fills and Put or the finalizer of a handle that
+// is never returned drains.
+type handlePool[H any, D any] struct {
+	mu      sync.Mutex
+	maxIdle int
+	inUse   chan struct{}
+	idle    map[string][]pooledHandle[H, D]
+	closed  bool
+	stats   PoolStats
+	free    func(H)
+}
+
+// init sets up a pool that keeps at most maxIdle idle liboqs objects per
+// algorithm, and hands out at most maxInUse at a time if it is positive.
+func (p *handlePool[H, D]) init(maxIdle, maxInUse int, free func(H)) {
+	p.maxIdle = maxIdle
+	p.free = free
+	if maxInUse > 0 {
+		p.inUse = make(chan struct{}, maxInUse)
+	}
+}
+
+// acquire waits until a handle may be handed out, and returns the function
+// that releases it, which may be invoked more than once.
+func (p *handlePool[H, D]) acquire() func() {
+	if p.inUse != nil {
+		p.inUse <- struct{}{}
+	}
+	p.mu.Lock()
+	p.stats.InUse++
+	p.mu.Unlock()
+	return sync.OnceFunc(func() {
+		p.mu.Lock()
+		p.stats.InUse--
+		p.mu.Unlock()
+		if p.inUse != nil {
+			<-p.inUse
+		}
+	})
+}
+
+// pooledHandle is an idle liboqs object together with its algorithm details.
+type pooledHandle[H any, D any] struct {
+	handle  H
+	details D
+}
+
+// get pops an idle liboqs object of the algorithm algName.
+func (p *handlePool[H, D]) get(algName string) (pooledHandle[H, D], bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Gets++
+	handles := p.idle[algName]
+	if len(handles) == 0 {
+		return pooledHandle[H, D]{}, false
+	}
+	h := handles[len(handles)-1]
+	p.idle[algName] = handles[:len(handles)-1]
+	p.stats.Hits++
+	p.stats.Idle--
+	return h, true
+}
+
+// put pushes an idle liboqs object of the algorithm algName, or frees it if
+// the pool is full or closed.
+func (p *handlePool[H, D]) put(algName string, h pooledHandle[H, D]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stats.Puts++
+	if p.closed || len(p.idle[algName]) >= p.maxIdle {
+		p.stats.Drops++
+		p.free(h.handle)
+		return
+	}
+	if p.idle == nil {
+		p.idle = make(map[string][]pooledHandle[H, D])
+	}
+	p.idle[algName] = append(p.idle[algName], h)
+	p.stats.Idle++
+}
+
+// snapshot returns the current statistics.
+func (p *handlePool[H, D]) snapshot() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+// close frees all the idle liboqs objects.
+func (p *handlePool[H, D]) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	for algName, handles := range p.idle {
+		for _, h := range handles {
+			p.free(h.handle)
+		}
+		delete(p.idle, algName)
+	}
+	p.stats.Idle = 0
+}
+
+// KEMPool recycles initialized KEMs, so that servers do not create a liboqs
+// object per connection. It is safe for concurrent use.
+type KEMPool struct {
+	pool handlePool[*C.OQS_KEM, KeyEncapsulationDetails]
+}
+
+// NewKEMPool creates a KEMPool that keeps at most maxIdle idle KEMs per
+// algorithm and, if maxInUse is positive, hands out at most maxInUse KEMs at
+// a time, for all algorithms.
+func NewKEMPool(maxIdle, maxInUse int) *KEMPool {
+	p := &KEMPool{}
+	p.pool.init(maxIdle, maxInUse, func(kem *C.OQS_KEM) { C.OQS_KEM_free(kem) })
+	return p
+}
+
+// Get returns an initialized KEM for the algorithm algName, without any
+// secret key. If maxInUse KEMs are in use, it blocks until one is returned.
+// It should be returned with Put once done; if it is not, a finalizer frees
+// it and releases its place as for NewKeyEncapsulation, as does its Clean
+// method.
+func (p *KEMPool) Get(algName string) (*KeyEncapsulation, error) {
+	release := p.pool.acquire()
+	h, ok := p.pool.get(algName)
+	if !ok {
+		kem, err := NewKeyEncapsulation(algName)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		kem.release = release
+		return kem, nil
+	}
+	kem := &KeyEncapsulation{
+		kem:        h.handle,
+		algDetails: h.details,
+		release:    release,
+		finalizer:  true,
+	}
+	runtime.SetFinalizer(kem, (*KeyEncapsulation).Clean)
+	return kem, nil
+}
+
+// Put cleanses the secret key of kem and returns its liboqs object to the
+// pool. kem must not be used afterwards: it is reset as if Clean was invoked,
+// hence any further operation fails with ErrNotInitialized.
+func (p *KEMPool) Put(kem *KeyEncapsulation) error {
+	if kem.kem == nil {
+		return newError(kem.algDetails.Name, "put", ErrNotInitialized)
+	}
+	h := pooledHandle[*C.OQS_KEM, KeyEncapsulationDetails]{
+		handle:  kem.kem,
+		details: kem.algDetails,
+	}
+	release := kem.release
+	kem.kem, kem.release = nil, nil // keep Clean from freeing the liboqs object
+	kem.Clean()
+	p.pool.put(h.details.Name, h)
+	if release != nil {
+		release()
+	}
+	return nil
+}
+
+// Stats returns the statistics of the pool.
+func (p *KEMPool) Stats() PoolStats {
+	return p.pool.snapshot()
+}
+
+// Close frees the idle KEMs. KEMs returned afterwards are freed immediately.
+func (p *KEMPool) Close() {
+	p.pool.close()
+}
+
+// SigPool recycles initialized signatures, so that servers do not create a
+// liboqs object per request. It is safe for concurrent use.
+type SigPool struct {
+	pool handlePool[*C.OQS_SIG, SignatureDetails]
+}
+
+// NewSigPool creates a SigPool that keeps at most maxIdle idle signatures per
+// algorithm and, if maxInUse is positive, hands out at most maxInUse
+// signatures at a time, for all algorithms.
+func NewSigPool(maxIdle, maxInUse int) *SigPool {
+	p := &SigPool{}
+	p.pool.init(maxIdle, maxInUse, func(sig *C.OQS_SIG) { C.OQS_SIG_free(sig) })
+	return p
+}
+
+// Get returns an initialized signature for the algorithm algName, without
+// any secret key. If maxInUse signatures are in use, it blocks until one is
+// returned. It should be returned with Put once done; if it is not, a
+// finalizer frees it and releases its place as for NewSignature, as does its
+// Clean method.
+func (p *SigPool) Get(algName string) (*Signature, error) {
+	release := p.pool.acquire()
+	h, ok := p.pool.get(algName)
+	if !ok {
+		sig, err := NewSignature(algName)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		sig.release = release
+		return sig, nil
+	}
+	sig := &Signature{
+		sig:        h.handle,
+		algDetails: h.details,
+		release:    release,
+		finalizer:  true,
+	}
+	runtime.SetFinalizer(sig, (*Signature).Clean)
+	return sig, nil
+}
+
+// Put cleanses the secret key of sig and returns its liboqs object to the
+// pool. sig must not be used afterwards: it is reset as if Clean was invoked,
+// hence any further operation fails with ErrNotInitialized.
+func (p *SigPool) Put(sig *Signature) error {
+	if sig.sig == nil {
+		return newError(sig.algDetails.Name, "put", ErrNotInitialized)
+	}
+	h := pooledHandle[*C.OQS_SIG, SignatureDetails]{
+		handle:  sig.sig,
+		details: sig.algDetails,
+	}
+	release := sig.release
+	sig.sig, sig.release = nil, nil // keep Clean from freeing the liboqs object
+	sig.Clean()
+	p.pool.put(h.details.Name, h)
+	if release != nil {
+		release()
+	}
+	return nil
+}
+
+// Stats returns the statistics of the pool.
+func (p *SigPool) Stats() PoolStats {
+	return p.pool.snapshot()
+}
+
+// Close frees the idle signatures. Signatures returned afterwards are freed
+// immediately.
+func (p *SigPool) Close() {
+	p.pool.close()
+}
+
+/**************** END Pools ****************/
@@ -0,0 +1,109 @@
+package oqs
+
+import "sync"
+
+/**************** Secret arenas ****************/
+
+// Small secret buffers are slots of arenas, i.e. secret memory mappings of
+// secretArenaSize bytes split into slots of a single power-of-two size, so
+// that the secret keys and shared secrets of a process share a few guarded
+// and locked mappings instead of getting one mapping, two guard pages and an
+// mlock(2) call each. Larger buffers get a dedicated mapping.
+//
+// This deliberately trades the guard pages around each buffer for a bounded
+// number of mappings: the guard pages of an arena surround the arena, not its
+// slots, so that an overflow of a buffer in the middle of an arena reaches the
+// next slot instead of faulting. A guard page per slot would split the arena
+// into two mappings per slot, as many as a dedicated mapping per buffer.
+const (
+	secretArenaSize = 64 << 10
+	secretMinSlot   = 64
+	secretMaxSlot   = secretArenaSize / 4
+)
+
+// secretArena is a secret memory mapping split into slots of slotSize bytes.
+type secretArena struct {
+	region   []byte // the whole memory mapping, or nil on the Go heap
+	data     []byte
+	locked   bool
+	slotSize int
+	free     []int // the indices of the free slots
+}
+
+var (
+	// secretArenas lists the arenas with free slots, by slot size.
+	secretArenas = make(map[int][]*secretArena)
+	// secretArenasMutex guards secretArenas and the free slots of the arenas.
+	secretArenasMutex sync.Mutex
+)
+
+// allocSecretSlot returns an arena and the index of one of its zeroed slots of
+// at least size bytes, where size <= secretMaxSlot. It maps a new arena if
+// none of the arenas of the slot size has a free slot.
+func allocSecretSlot(size int) (*secretArena, int, error) {
+	slotSize := secretMinSlot
+	for slotSize < size {
+		slotSize *= 2
+	}
+	secretArenasMutex.Lock()
+	defer secretArenasMutex.Unlock()
+	arenas := secretArenas[slotSize]
+	if len(arenas) == 0 {
+		region, data, locked, err := mapSecretMemory(secretArenaSize)
+		if err != nil {
+			return nil, 0, err
+		}
+		a := &secretArena{
+			region:   region,
+			data:     data,
+			locked:   locked,
+			slotSize: slotSize,
+		}
+		for slot := secretArenaSize/slotSize - 1; slot >= 0; slot-- {
+			a.free = append(a.free, slot)
+		}
+		arenas = append(arenas, a)
+	}
+	a := arenas[len(arenas)-1]
+	slot := a.free[len(a.free)-1]
+	a.free = a.free[:len(a.free)-1]
+	if len(a.free) == 0 {
+		arenas = arenas[:len(arenas)-1]
+	}
+	secretArenas[slotSize] = arenas
+	return a, slot, nil
+}
+
+// slotBytes returns the first size bytes of a slot of the arena.
+func (a *secretArena) slotBytes(slot, size int) []byte {
+	offset := slot * a.slotSize
+	return a.data[offset : offset+size : offset+size]
+}
+
+// freeSecretSlot returns a cleansed slot to its arena. An arena whose slots
+// are all free is unmapped, unless it is the only arena of its slot size with
+// free slots, so that a process which allocates and destroys a buffer at a
+// time does not map and unmap an arena each time.
+func freeSecretSlot(a *secretArena, slot int) {
+	secretArenasMutex.Lock()
+	defer secretArenasMutex.Unlock()
+	arenas := secretArenas[a.slotSize]
+	if len(a.free) == 0 {
+		arenas = append(arenas, a)
+	}
+	a.free = append(a.free, slot)
+	if len(a.free) == secretArenaSize/a.slotSize && len(arenas) > 1 {
+		for i := range arenas {
+			if arenas[i] == a {
+				arenas = append(arenas[:i], arenas[i+1:]...)
+				break
+			}
+		}
+		if a.region != nil {
+			unmapSecretMemory(a.region)
+		}
+	}
+	secretArenas[a.slotSize] = arenas
+}
+
+/**************** END Secret arenas ****************/
@@ -0,0 +1,123 @@
+package oqs
+
+import (
+	"fmt"
+	"runtime"
+)
+
+/**************** Secret buffers ****************/
+
+// SecretBuffer is a fixed-size buffer for secret data, such as secret keys and
+// shared secrets, that lives outside the Go heap, hence is never moved or
+// copied by the garbage collector. On Unix systems, it lives in anonymous
+// memory mappings surrounded by inaccessible guard pages, locked into RAM with
+// mlock(2) so that it is never swapped out and, on Linux, excluded from core
+// dumps; buffers of up to 16 KiB share the mappings of a few arenas, larger
+// ones get a dedicated mapping. Elsewhere, it falls back to the Go heap.
+//
+// Destroy cleanses and releases the buffer, and should be invoked once the
+// buffer is no longer needed; a buffer that becomes unreachable without being
+// destroyed is destroyed by a finalizer. As the garbage collector does not
+// track the slices returned by Bytes, such a slice is valid only until Destroy
+// is invoked or the SecretBuffer is garbage collected, much like the file
+// descriptor returned by os.File.Fd: keep the SecretBuffer reachable while
+// using its content, e.g. with a deferred Destroy or runtime.KeepAlive. Past
+// that, the memory the slice refers to may be unmapped or reused. A
+// SecretBuffer is not safe for concurrent use.
+type SecretBuffer struct {
+	arena  *secretArena // the arena of the slot, or nil
+	slot   int
+	region []byte // the dedicated memory mapping, or nil
+	data   []byte
+	locked bool
+}
+
+// NewSecretBuffer allocates a zeroed SecretBuffer of size bytes. If the
+// memory cannot be locked, e.g. because RLIMIT_MEMLOCK is exceeded, the
+// buffer is still returned; see SecretBuffer.Locked.
+func NewSecretBuffer(size int) (*SecretBuffer, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("%w: negative size", ErrSecretMemory)
+	}
+	b := new(SecretBuffer)
+	var err error
+	switch {
+	case size == 0:
+	case size <= secretMaxSlot:
+		b.arena, b.slot, err = allocSecretSlot(size)
+		if err == nil {
+			b.data, b.locked = b.arena.slotBytes(b.slot, size), b.arena.locked
+		}
+	default:
+		b.region, b.data, b.locked, err = mapSecretMemory(size)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSecretMemory, err)
+	}
+	// The buffer is the only reference to its slot or mapping, hence the
+	// finalizer may return them once the buffer is unreachable.
+	runtime.SetFinalizer(b, (*SecretBuffer).Destroy)
+	return b, nil
+}
+
+// Bytes returns the content of the buffer, which may be written to, or nil
+// once the buffer is destroyed. Its capacity is its length, hence appending
+// to it copies it to the Go heap.
+func (b *SecretBuffer) Bytes() []byte {
+	return b.data
+}
+
+// Len returns the size of the buffer.
+func (b *SecretBuffer) Len() int {
+	return len(b.data)
+}
+
+// Locked reports whether the buffer is locked into RAM. It returns false on
+// a nil buffer.
+func (b *SecretBuffer) Locked() bool {
+	return b != nil && b.locked
+}
+
+// Destroy cleanses the buffer with OQS_MEM_cleanse and releases its memory.
+// Destroy is idempotent, and a no-op on a nil buffer.
+func (b *SecretBuffer) Destroy() {
+	if b == nil {
+		return
+	}
+	MemCleanse(b.data)
+	switch {
+	case b.arena != nil:
+		freeSecretSlot(b.arena, b.slot)
+	case b.region != nil:
+		unmapSecretMemory(b.region)
+	}
+	*b = SecretBuffer{}
+	runtime.SetFinalizer(b, nil)
+}
+
+// replaceSecretKey destroys old, if any, and returns a new SecretBuffer that
+// holds keyLength bytes for a secret key, copied from secretKey if not nil,
+// followed by a copy of seed, together with the corresponding slices of the
+// buffer. It returns nil values if keyLength is 0 and seed is empty.
+func replaceSecretKey(old *SecretBuffer, secretKey []byte, keyLength int,
+	seed []byte,
+) (buf *SecretBuffer, key, keySeed []byte, err error) {
+	old.Destroy()
+	if keyLength == 0 && len(seed) == 0 {
+		return nil, nil, nil, nil
+	}
+	buf, err = NewSecretBuffer(keyLength + len(seed))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data := buf.Bytes()
+	key = data[:keyLength:keyLength]
+	copy(key, secretKey)
+	if len(seed) > 0 {
+		keySeed = data[keyLength:]
+		copy(keySeed, seed)
+	}
+	return buf, key, keySeed, nil
+}
+
+/**************** END Secret buffers ****************/
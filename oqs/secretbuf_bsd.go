@@ -0,0 +1,6 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package oqs
+
+// excludeFromCoreDump is not supported on this platform.
+func excludeFromCoreDump([]byte) {}
@@ -0,0 +1,8 @@
+package oqs
+
+import "golang.org/x/sys/unix"
+
+// excludeFromCoreDump excludes pages of secret memory from core dumps.
+func excludeFromCoreDump(pages []byte) {
+	_ = unix.Madvise(pages, unix.MADV_DONTDUMP)
+}
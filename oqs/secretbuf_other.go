@@ -0,0 +1,12 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package oqs
+
+// mapSecretMemory falls back to the Go heap on this platform.
+func mapSecretMemory(size int) (region, data []byte, locked bool, err error) {
+	return nil, make([]byte, size), false, nil
+}
+
+// unmapSecretMemory is never invoked on this platform, as mapSecretMemory
+// maps no region.
+func unmapSecretMemory([]byte) {}
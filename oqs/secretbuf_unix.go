@@ -0,0 +1,40 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package oqs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mapSecretMemory maps size bytes of anonymous memory between two guard
+// pages, and locks them into RAM if possible. The data are placed at the end
+// of their pages, so that overflows fault on the trailing guard page.
+func mapSecretMemory(size int) (region, data []byte, locked bool, err error) {
+	page := os.Getpagesize()
+	dataLength := (size + page - 1) / page * page
+	region, err = unix.Mmap(-1, 0, dataLength+2*page,
+		unix.PROT_READ|unix.PROT_WRITE, unix.MAP_PRIVATE|unix.MAP_ANON)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if err := unix.Mprotect(region[:page], unix.PROT_NONE); err != nil {
+		_ = unix.Munmap(region)
+		return nil, nil, false, err
+	}
+	if err := unix.Mprotect(region[page+dataLength:], unix.PROT_NONE); err != nil {
+		_ = unix.Munmap(region)
+		return nil, nil, false, err
+	}
+	pages := region[page : page+dataLength]
+	locked = unix.Mlock(pages) == nil
+	excludeFromCoreDump(pages)
+	return region, pages[dataLength-size : dataLength : dataLength], locked, nil
+}
+
+// unmapSecretMemory unmaps a region mapped by mapSecretMemory, which also
+// unlocks it.
+func unmapSecretMemory(region []byte) {
+	_ = unix.Munmap(region)
+}
@@ -0,0 +1,227 @@
+package oqs
+
+import (
+	"crypto"
+	"crypto/subtle"
+	"io"
+)
+
+/**************** crypto.Signer ****************/
+
+// SignerOpts implements crypto.SignerOpts for SigPrivateKey.Sign and
+// SigPublicKey.Verify. Hash must be zero, since the message is signed as is.
+// Context is passed to Signature.SignWithCtxStr or
+// Signature.VerifyWithCtxStr, and must be empty for algorithms that do not
+// support context strings.
+//
+// The pre-hash variants HashML-DSA (FIPS 204) and HashSLH-DSA (FIPS 205) are
+// not available: they sign 0x01 || len(ctx) || ctx || OID(PH) || PH(M) with
+// the internal signing function, whereas the liboqs API only signs with the
+// 0x00 (pure) domain separator, and its SLH-DSA pre-hash algorithms hash the
+// whole message themselves. Streaming and pre-hash signing (SignReader,
+// VerifyReader) are blocked on liboqs exposing the internal or pre-hashed
+// signing functions.
+type SignerOpts struct {
+	Hash    crypto.Hash
+	Context []byte
+}
+
+// HashFunc returns opts.Hash, as required by crypto.SignerOpts.
+func (opts *SignerOpts) HashFunc() crypto.Hash {
+	return opts.Hash
+}
+
+// signerContext extracts the context string from opts, and rejects any
+// pre-hashing request.
+func signerContext(algName, op string, opts crypto.SignerOpts) ([]byte, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	if opts.HashFunc() != 0 {
+		return nil, newError(algName, op, ErrUnsupportedHash)
+	}
+	if o, ok := opts.(*SignerOpts); ok {
+		return o.Context, nil
+	}
+	return nil, nil
+}
+
+// SigPublicKey is a signature public key tagged with its algorithm name. It
+// implements crypto.PublicKey.
+type SigPublicKey struct {
+	algName   string
+	publicKey []byte
+}
+
+// NewSigPublicKey wraps publicKey for the signature algorithm algName. The
+// public key is copied and its length is validated.
+func NewSigPublicKey(algName string, publicKey []byte) (*SigPublicKey, error) {
+	sig, err := NewSignature(algName)
+	if err != nil {
+		return nil, err
+	}
+	defer sig.Clean()
+	if len(publicKey) != sig.Details().LengthPublicKey {
+		return nil, newError(algName, "public key", ErrInvalidPublicKeyLength)
+	}
+	return &SigPublicKey{
+		algName:   algName,
+		publicKey: append([]byte(nil), publicKey...),
+	}, nil
+}
+
+// Algorithm returns the signature algorithm name.
+func (pub *SigPublicKey) Algorithm() string {
+	return pub.algName
+}
+
+// Bytes returns a copy of the raw public key.
+func (pub *SigPublicKey) Bytes() []byte {
+	return append([]byte(nil), pub.publicKey...)
+}
+
+// Equal reports whether pub and x have the same algorithm and value.
+func (pub *SigPublicKey) Equal(x crypto.PublicKey) bool {
+	xx, ok := x.(*SigPublicKey)
+	if !ok {
+		return false
+	}
+	return pub.algName == xx.algName &&
+		subtle.ConstantTimeCompare(pub.publicKey, xx.publicKey) == 1
+}
+
+// Verify verifies the validity of a signed message, returning true if the
+// signature is valid, and false otherwise. opts may be nil, or a *SignerOpts
+// carrying a context string.
+func (pub *SigPublicKey) Verify(message, signature []byte,
+	opts crypto.SignerOpts,
+) (bool, error) {
+	context, err := signerContext(pub.algName, "verify", opts)
+	if err != nil {
+		return false, err
+	}
+	verifier, err := NewSignature(pub.algName)
+	if err != nil {
+		return false, err
+	}
+	defer verifier.Clean()
+	if len(context) > 0 {
+		return verifier.VerifyWithCtxStr(message, signature, context,
+			pub.publicKey)
+	}
+	return verifier.Verify(message, signature, pub.publicKey)
+}
+
+// SigPrivateKey is a signature secret key together with its public key. It
+// implements crypto.Signer, hence it can be used wherever the standard library
+// expects one.
+type SigPrivateKey struct {
+	signer *Signature
+	public *SigPublicKey
+}
+
+// GenerateSigKey generates a fresh key pair for the signature algorithm
+// algName.
+func GenerateSigKey(algName string) (*SigPrivateKey, error) {
+	signer, err := NewSignature(algName)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, err := signer.GenerateKeyPair()
+	if err != nil {
+		signer.Clean()
+		return nil, err
+	}
+	return &SigPrivateKey{
+		signer: signer,
+		public: &SigPublicKey{algName: algName, publicKey: publicKey},
+	}, nil
+}
+
+// NewSigPrivateKey wraps an existing secret key/public key pair for the
+// signature algorithm algName. Both keys are copied, and are checked to form
+// a key pair with Signature.ValidateKeyPair.
+func NewSigPrivateKey(algName string, secretKey, publicKey []byte) (
+	*SigPrivateKey, error,
+) {
+	public, err := NewSigPublicKey(algName, publicKey)
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewSignature(algName, WithSecretKey(secretKey))
+	if err != nil {
+		return nil, err
+	}
+	if err := signer.ValidateKeyPair(public.publicKey); err != nil {
+		signer.Clean()
+		return nil, err
+	}
+	return &SigPrivateKey{signer: signer, public: public}, nil
+}
+
+// Algorithm returns the signature algorithm name.
+func (priv *SigPrivateKey) Algorithm() string {
+	return priv.public.algName
+}
+
+// Public returns the corresponding *SigPublicKey, as required by
+// crypto.Signer.
+func (priv *SigPrivateKey) Public() crypto.PublicKey {
+	return priv.public
+}
+
+// ExportSecretKey returns a copy of the secret key.
+func (priv *SigPrivateKey) ExportSecretKey() []byte {
+	return priv.signer.ExportSecretKey()
+}
+
+// ExportSecretKeyTo copies the secret key into dst, see
+// Signature.ExportSecretKeyTo.
+func (priv *SigPrivateKey) ExportSecretKeyTo(dst []byte) (int, error) {
+	return priv.signer.ExportSecretKeyTo(dst)
+}
+
+// Equal reports whether priv and x have the same algorithm and value.
+func (priv *SigPrivateKey) Equal(x crypto.PrivateKey) bool {
+	xx, ok := x.(*SigPrivateKey)
+	if !ok {
+		return false
+	}
+	return priv.public.Equal(xx.public) &&
+		subtle.ConstantTimeCompare(priv.signer.secretKey,
+			xx.signer.secretKey) == 1
+}
+
+// Sign signs message and returns the corresponding signature, as required by
+// crypto.Signer. Since the signature algorithms of liboqs hash the message
+// internally, message must not be pre-hashed, and opts.HashFunc() must return
+// zero. opts may be nil, or a *SignerOpts carrying a context string. The rand
+// argument is ignored, liboqs draws randomness from its own RNG, see
+// RandomBytesSwitchAlgorithm.
+func (priv *SigPrivateKey) Sign(rand io.Reader, message []byte,
+	opts crypto.SignerOpts,
+) ([]byte, error) {
+	context, err := signerContext(priv.public.algName, "sign", opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(context) > 0 {
+		return priv.signer.SignWithCtxStr(message, context)
+	}
+	return priv.signer.Sign(message)
+}
+
+// Verify verifies a signature against the public key of priv, see
+// SigPublicKey.Verify.
+func (priv *SigPrivateKey) Verify(message, signature []byte,
+	opts crypto.SignerOpts,
+) (bool, error) {
+	return priv.public.Verify(message, signature, opts)
+}
+
+// Clean zeroes-in the secret key and frees the underlying Signature.
+func (priv *SigPrivateKey) Clean() {
+	priv.signer.Clean()
+}
+
+/**************** END crypto.Signer ****************/
@@ -0,0 +1,517 @@
+package oqs
+
+/*
+#include <stdlib.h>
+#include <oqs/oqs.h>
+void setStoreSecretKey_cgo(OQS_SIG_STFL_SECRET_KEY*, uintptr_t);
+*/
+import "C"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/cgo"
+	"sync"
+	"unsafe"
+)
+
+/**************** Stateful sigs ****************/
+
+// List of enabled stateful signature algorithms, populated by init().
+var enabledStatefulSigs []string
+
+// List of supported stateful signature algorithms, populated by init().
+var supportedStatefulSigs []string
+
+// MaxNumberStatefulSigs returns the maximum number of supported stateful
+// signature algorithms.
+func MaxNumberStatefulSigs() int {
+	return int(C.OQS_SIG_STFL_alg_count())
+}
+
+// IsStatefulSigEnabled returns true if a stateful signature algorithm is
+// enabled, and false otherwise.
+func IsStatefulSigEnabled(algName string) bool {
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	result := C.OQS_SIG_STFL_alg_is_enabled(cAlgName)
+	return result != 0
+}
+
+// IsStatefulSigSupported returns true if a stateful signature algorithm is
+// supported, and false otherwise.
+func IsStatefulSigSupported(algName string) bool {
+	for i := range supportedStatefulSigs {
+		if supportedStatefulSigs[i] == algName {
+			return true
+		}
+	}
+	return false
+}
+
+// StatefulSigName returns the stateful signature algorithm name from its
+// corresponding numerical ID.
+func StatefulSigName(algID int) (string, error) {
+	if algID >= MaxNumberStatefulSigs() {
+		return "", newError("", "name", ErrAlgorithmIDOutOfRange)
+	}
+	return C.GoString(C.OQS_SIG_STFL_alg_identifier(C.size_t(algID))), nil
+}
+
+// SupportedStatefulSigs returns the list of supported stateful signature
+// algorithms.
+func SupportedStatefulSigs() []string {
+	return supportedStatefulSigs
+}
+
+// EnabledStatefulSigs returns the list of enabled stateful signature
+// algorithms.
+func EnabledStatefulSigs() []string {
+	return enabledStatefulSigs
+}
+
+// Initializes the lists enabledStatefulSigs and supportedStatefulSigs.
+func init() {
+	for i := 0; i < MaxNumberStatefulSigs(); i++ {
+		sigName, _ := StatefulSigName(i)
+		supportedStatefulSigs = append(supportedStatefulSigs, sigName)
+		if IsStatefulSigEnabled(sigName) {
+			enabledStatefulSigs = append(enabledStatefulSigs, sigName)
+		}
+	}
+}
+
+/**************** END Stateful sigs ****************/
+
+/**************** StatefulSignature ****************/
+
+// StatefulSignatureDetails defines the stateful signature algorithm details.
+type StatefulSignatureDetails struct {
+	Name               string
+	Version            string
+	IsEUFCMA           bool
+	LengthPublicKey    int
+	LengthSecretKey    int
+	MaxLengthSignature int
+}
+
+// String converts the stateful signature algorithm details to a string
+// representation.
+func (sigDetails StatefulSignatureDetails) String() string {
+	return fmt.Sprintf("Name: %s\n"+
+		"Version: %s\n"+
+		"Is EUF_CMA: %v\n"+
+		"Length public key (bytes): %d\n"+
+		"Length secret key (bytes): %d\n"+
+		"Maximum length signature (bytes): %d",
+		sigDetails.Name,
+		sigDetails.Version,
+		sigDetails.IsEUFCMA,
+		sigDetails.LengthPublicKey,
+		sigDetails.LengthSecretKey,
+		sigDetails.MaxLengthSignature)
+}
+
+// SecretKeyStore durably persists the state of a stateful secret key. Store
+// is invoked with the serialized secret key after every state update, and
+// must not return before the state is on stable storage; a signature is only
+// released once Store succeeded. The secretKey slice is cleansed after Store
+// returns, hence it must not be retained.
+type SecretKeyStore interface {
+	Store(secretKey []byte) error
+}
+
+// secretKeyLoader is implemented by the stores that can read back the
+// persisted state, such as FileSecretKeyStore.
+type secretKeyLoader interface {
+	Load() ([]byte, error)
+}
+
+// secretKeyStoreContext is the context passed to the liboqs store callback.
+// It is referenced by a cgo.Handle instead of the StatefulSignature itself,
+// so that the latter remains collectable by its finalizer.
+type secretKeyStoreContext struct {
+	store SecretKeyStore
+	err   error // error returned by the last invocation of store.Store
+}
+
+// StatefulSignature defines the stateful (XMSS, XMSS^MT, LMS/HSS) signature
+// main data structure. Its methods are safe for concurrent use.
+//
+// Key generation and signing require liboqs to be built with
+// OQS_ALLOW_STFL_KEY_AND_SIG_GEN; otherwise only Verify is available.
+type StatefulSignature struct {
+	mu         sync.Mutex
+	sig        *C.OQS_SIG_STFL
+	secretKey  *C.OQS_SIG_STFL_SECRET_KEY
+	storeCtx   *secretKeyStoreContext
+	handle     cgo.Handle
+	algDetails StatefulSignatureDetails
+	fileStore  *FileSecretKeyStore // set by NewStatefulSignatureFile
+	hasState   bool                // true if the secret key holds a state
+	finalizer  bool                // true if a finalizer was set by NewStatefulSignature
+}
+
+// String converts the stateful signature algorithm name to a string
+// representation.
+func (sig *StatefulSignature) String() string {
+	return fmt.Sprintf("Stateful signature mechanism: %s",
+		sig.algDetails.Name)
+}
+
+// NewStatefulSignatureFile allocates and initializes a stateful signature
+// with an algorithm name whose secret key state is persisted to the key file
+// path by a FileSecretKeyStore, the default store. The persisted state, if
+// any, is imported, unless opts include WithSecretKey; otherwise, the caller
+// must invoke StatefulSignature.GenerateKeyPair. StatefulSignature.Clean also
+// closes the store, releasing the lock on the key file.
+func NewStatefulSignatureFile(algName, path string, opts ...Option) (
+	*StatefulSignature, error,
+) {
+	store, err := NewFileSecretKeyStore(path)
+	if err != nil {
+		return nil, newError(algName, "init",
+			fmt.Errorf("%w: %w", ErrSecretKeyStore, err))
+	}
+	persisted, err := store.Load()
+	switch {
+	case err == nil && len(persisted) > 0:
+		// later options, e.g. WithSecretKey, take precedence
+		opts = append([]Option{WithSecretKey(persisted)}, opts...)
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		store.Close()
+		return nil, newError(algName, "init",
+			fmt.Errorf("%w: %w", ErrSecretKeyStore, err))
+	}
+	sig, err := NewStatefulSignature(algName, store, opts...)
+	MemCleanse(persisted)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	sig.fileStore = store
+	return sig, nil
+}
+
+// NewStatefulSignature allocates and initializes a stateful signature with an
+// algorithm name and a secret key state store, configured by opts (e.g.
+// WithSecretKey, with a secret key previously serialized by
+// ExportSecretKey or persisted by store). The store is mandatory; see
+// NewStatefulSignatureFile for the default FileSecretKeyStore. The caller
+// should invoke StatefulSignature.Clean once done; a finalizer frees the
+// underlying liboqs objects if the caller forgets to.
+func NewStatefulSignature(algName string, store SecretKeyStore,
+	opts ...Option,
+) (*StatefulSignature, error) {
+	if store == nil {
+		return nil, newError(algName, "init", ErrNilSecretKeyStore)
+	}
+	if !IsStatefulSigEnabled(algName) {
+		if IsStatefulSigSupported(algName) {
+			return nil, newError(algName, "init", ErrAlgorithmNotEnabled)
+		}
+		return nil, newError(algName, "init", ErrAlgorithmNotSupported)
+	}
+	o := newOptions(opts)
+
+	sig := new(StatefulSignature)
+	cAlgName := C.CString(algName)
+	defer C.free(unsafe.Pointer(cAlgName))
+	sig.sig = C.OQS_SIG_STFL_new(cAlgName)
+	sig.secretKey = C.OQS_SIG_STFL_SECRET_KEY_new(cAlgName)
+	sig.storeCtx = &secretKeyStoreContext{store: store}
+	sig.handle = cgo.NewHandle(sig.storeCtx)
+	sig.finalizer = true
+	runtime.SetFinalizer(sig, (*StatefulSignature).Clean)
+	if sig.sig == nil || sig.secretKey == nil {
+		sig.Clean()
+		return nil, newError(algName, "init", ErrLiboqsFailure)
+	}
+
+	sig.algDetails.Name = C.GoString(sig.sig.method_name)
+	sig.algDetails.Version = C.GoString(sig.sig.alg_version)
+	sig.algDetails.IsEUFCMA = bool(sig.sig.euf_cma)
+	sig.algDetails.LengthPublicKey = int(sig.sig.length_public_key)
+	sig.algDetails.LengthSecretKey = int(sig.sig.length_secret_key)
+	sig.algDetails.MaxLengthSignature = int(sig.sig.length_signature)
+
+	if len(o.secretKey) > 0 {
+		rv := C.OQS_SIG_STFL_SECRET_KEY_deserialize(
+			sig.secretKey,
+			bytesPtr(o.secretKey),
+			C.size_t(len(o.secretKey)),
+			nil,
+		)
+		if rv != C.OQS_SUCCESS {
+			sig.Clean()
+			return nil, &Error{Alg: algName, Op: "init", Status: int(rv),
+				Err: ErrInvalidSecretKey}
+		}
+		sig.hasState = true
+	}
+	C.setStoreSecretKey_cgo(sig.secretKey, C.uintptr_t(sig.handle))
+
+	return sig, nil
+}
+
+// Details returns the stateful signature algorithm details.
+func (sig *StatefulSignature) Details() StatefulSignatureDetails {
+	return sig.algDetails
+}
+
+// GenerateKeyPair generates a pair of secret key/public key and returns the
+// public key. The secret key is stored inside the sig receiver, and its
+// initial state is persisted to the secret key state store. To never
+// overwrite a state in use, GenerateKeyPair fails with ErrSecretKeyExists if
+// the sig receiver already holds a secret key, or if the store has a Load
+// method, like FileSecretKeyStore, that returns a persisted state.
+func (sig *StatefulSignature) GenerateKeyPair() ([]byte, error) {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.sig == nil {
+		return nil, newError("", "keypair", ErrNotInitialized)
+	}
+	if sig.hasState {
+		return nil, newError(sig.algDetails.Name, "keypair",
+			ErrSecretKeyExists)
+	}
+	if loader, ok := sig.storeCtx.store.(secretKeyLoader); ok {
+		persisted, err := loader.Load()
+		switch {
+		case err == nil && len(persisted) > 0:
+			MemCleanse(persisted)
+			return nil, newError(sig.algDetails.Name, "keypair",
+				ErrSecretKeyExists)
+		case err != nil && !errors.Is(err, os.ErrNotExist):
+			return nil, newError(sig.algDetails.Name, "keypair",
+				fmt.Errorf("%w: %w", ErrSecretKeyStore, err))
+		}
+	}
+
+	publicKey := make([]byte, sig.algDetails.LengthPublicKey)
+	rv := C.OQS_SIG_STFL_keypair(
+		sig.sig,
+		bytesPtr(publicKey),
+		sig.secretKey,
+	)
+	runtime.KeepAlive(sig)
+
+	if rv != C.OQS_SUCCESS {
+		return nil, newStatusError(sig.algDetails.Name, "keypair", int(rv))
+	}
+	sig.hasState = true
+
+	secretKey, err := sig.serialize("keypair")
+	if err != nil {
+		return nil, err
+	}
+	defer MemCleanse(secretKey)
+	if err := sig.storeCtx.store.Store(secretKey); err != nil {
+		return nil, newError(sig.algDetails.Name, "keypair",
+			fmt.Errorf("%w: %w", ErrSecretKeyStore, err))
+	}
+
+	return publicKey, nil
+}
+
+// ExportSecretKey serializes the current state of the secret key. A
+// serialized state must be imported at most once, as signing with two copies
+// of the same state reuses one-time keys.
+func (sig *StatefulSignature) ExportSecretKey() ([]byte, error) {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.sig == nil {
+		return nil, newError("", "export", ErrNotInitialized)
+	}
+	return sig.serialize("export")
+}
+
+// serialize returns a copy of the serialized secret key; sig.mu must be
+// held.
+func (sig *StatefulSignature) serialize(op string) ([]byte, error) {
+	var buf *C.uint8_t
+	var lenBuf C.size_t
+	rv := C.OQS_SIG_STFL_SECRET_KEY_serialize(&buf, &lenBuf, sig.secretKey)
+	runtime.KeepAlive(sig)
+	if rv != C.OQS_SUCCESS {
+		return nil, newStatusError(sig.algDetails.Name, op, int(rv))
+	}
+	defer C.OQS_MEM_secure_free(unsafe.Pointer(buf), lenBuf)
+	return C.GoBytes(unsafe.Pointer(buf), C.int(lenBuf)), nil
+}
+
+// Sign signs a message and returns the corresponding signature. The updated
+// secret key state is persisted to the secret key state store before the
+// signature is returned; if that fails, no signature is returned and the
+// consumed one-time key is never used again.
+func (sig *StatefulSignature) Sign(message []byte) ([]byte, error) {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.sig == nil {
+		return nil, newError("", "sign", ErrNotInitialized)
+	}
+
+	remaining, err := sig.sigsRemaining()
+	if err != nil {
+		return nil, err
+	}
+	if remaining == 0 {
+		return nil, newError(sig.algDetails.Name, "sign",
+			ErrSignaturesExhausted)
+	}
+
+	signature := make([]byte, sig.algDetails.MaxLengthSignature)
+	var lenSig C.size_t
+	sig.storeCtx.err = nil
+	rv := C.OQS_SIG_STFL_sign(
+		sig.sig,
+		bytesPtr(signature),
+		&lenSig,
+		bytesPtr(message),
+		C.size_t(len(message)),
+		sig.secretKey,
+	)
+	runtime.KeepAlive(sig)
+
+	if storeErr := sig.storeCtx.err; storeErr != nil {
+		MemCleanse(signature)
+		return nil, &Error{Alg: sig.algDetails.Name, Op: "sign",
+			Status: int(rv),
+			Err:    fmt.Errorf("%w: %w", ErrSecretKeyStore, storeErr)}
+	}
+	if rv != C.OQS_SUCCESS {
+		MemCleanse(signature)
+		return nil, newStatusError(sig.algDetails.Name, "sign", int(rv))
+	}
+
+	return signature[:lenSig], nil
+}
+
+// Verify verifies the validity of a signed message, returning true if the
+// signature is valid, and false otherwise. Verify does not require a secret
+// key.
+func (sig *StatefulSignature) Verify(message []byte, signature []byte,
+	publicKey []byte,
+) (bool, error) {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.sig == nil {
+		return false, newError("", "verify", ErrNotInitialized)
+	}
+
+	if len(publicKey) != sig.algDetails.LengthPublicKey {
+		return false, newError(sig.algDetails.Name, "verify",
+			ErrInvalidPublicKeyLength)
+	}
+	if len(signature) > sig.algDetails.MaxLengthSignature {
+		return false, newError(sig.algDetails.Name, "verify",
+			ErrInvalidSignatureLength)
+	}
+
+	rv := C.OQS_SIG_STFL_verify(
+		sig.sig,
+		bytesPtr(message),
+		C.size_t(len(message)),
+		bytesPtr(signature),
+		C.size_t(len(signature)),
+		bytesPtr(publicKey),
+	)
+	runtime.KeepAlive(sig)
+
+	if rv != C.OQS_SUCCESS {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// SigsRemaining returns the number of signatures that the secret key can
+// still produce.
+func (sig *StatefulSignature) SigsRemaining() (uint64, error) {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.sig == nil {
+		return 0, newError("", "sigs remaining", ErrNotInitialized)
+	}
+	return sig.sigsRemaining()
+}
+
+// sigsRemaining implements SigsRemaining; sig.mu must be held.
+func (sig *StatefulSignature) sigsRemaining() (uint64, error) {
+	var remaining C.ulonglong
+	rv := C.OQS_SIG_STFL_sigs_remaining(sig.sig, &remaining, sig.secretKey)
+	runtime.KeepAlive(sig)
+	if rv != C.OQS_SUCCESS {
+		return 0, newStatusError(sig.algDetails.Name, "sigs remaining",
+			int(rv))
+	}
+	return uint64(remaining), nil
+}
+
+// SigsTotal returns the total number of signatures that the secret key can
+// produce.
+func (sig *StatefulSignature) SigsTotal() (uint64, error) {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.sig == nil {
+		return 0, newError("", "sigs total", ErrNotInitialized)
+	}
+	var total C.ulonglong
+	rv := C.OQS_SIG_STFL_sigs_total(sig.sig, &total, sig.secretKey)
+	runtime.KeepAlive(sig)
+	if rv != C.OQS_SUCCESS {
+		return 0, newStatusError(sig.algDetails.Name, "sigs total", int(rv))
+	}
+	return uint64(total), nil
+}
+
+// Clean frees the secret key and the underlying liboqs objects, and closes the
+// store opened by NewStatefulSignatureFile. The sig receiver must not be used
+// afterwards. Clean is idempotent, hence it is safe
+// to invoke it more than once.
+func (sig *StatefulSignature) Clean() {
+	sig.mu.Lock()
+	defer sig.mu.Unlock()
+	if sig.finalizer {
+		runtime.SetFinalizer(sig, nil)
+	}
+	if sig.secretKey != nil {
+		C.OQS_SIG_STFL_SECRET_KEY_free(sig.secretKey)
+	}
+	if sig.sig != nil {
+		C.OQS_SIG_STFL_free(sig.sig)
+	}
+	if sig.storeCtx != nil {
+		sig.handle.Delete()
+	}
+	if sig.fileStore != nil {
+		sig.fileStore.Close()
+	}
+	sig.fileStore = nil
+	sig.sig = nil
+	sig.secretKey = nil
+	sig.storeCtx = nil
+	sig.algDetails = StatefulSignatureDetails{}
+	sig.finalizer = false
+}
+
+// storeSecretKey is invoked by liboqs with the serialized secret key whenever
+// the state changes, before a signature is released.
+//
+//export storeSecretKey
+func storeSecretKey(skBuf *C.uint8_t, bufLen C.size_t,
+	handle C.uintptr_t,
+) C.OQS_STATUS {
+	storeCtx := cgo.Handle(handle).Value().(*secretKeyStoreContext)
+	secretKey := C.GoBytes(unsafe.Pointer(skBuf), C.int(bufLen))
+	defer MemCleanse(secretKey)
+	if err := storeCtx.store.Store(secretKey); err != nil {
+		storeCtx.err = err
+		return C.OQS_ERROR
+	}
+	return C.OQS_SUCCESS
+}
+
+/**************** END StatefulSignature ****************/
@@ -0,0 +1,195 @@
+package x509pq
+
+import (
+	"crypto"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+/**************** Certificate requests ****************/
+
+// certificateRequest is the ASN.1 structure of a PKCS#10 request.
+type certificateRequest struct {
+	TBSCertificateRequest asn1.RawValue
+	SignatureAlgorithm    pkix.AlgorithmIdentifier
+	SignatureValue        asn1.BitString
+}
+
+// tbsCertificateRequest is the ASN.1 structure of a PKCS#10
+// CertificationRequestInfo.
+type tbsCertificateRequest struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKey     asn1.RawValue
+	RawAttributes []asn1.RawValue `asn1:"tag:0"`
+}
+
+// attribute is the ASN.1 structure of a PKCS#10 attribute.
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// CertificateRequest is a parsed PKCS#10 certificate signing request whose
+// public key and signature use the algorithms of the oqs package. It also
+// serves as a template for CreateCertificateRequest, in which case only the
+// fields documented as template fields are used.
+type CertificateRequest struct {
+	Raw                      []byte // complete ASN.1 DER content
+	RawTBSCertificateRequest []byte // certificate request info part of Raw
+	RawSubjectPublicKeyInfo  []byte // DER encoded SubjectPublicKeyInfo
+	RawSubject               []byte // DER encoded subject
+
+	Signature          []byte
+	SignatureAlgorithm string // oqs algorithm name of the signature
+
+	PublicKeyAlgorithm string // oqs algorithm name of the public key
+	// PublicKey is a *oqs.SigPublicKey or *oqs.CompositePublicKey.
+	PublicKey crypto.PublicKey
+
+	Version int
+
+	// Template fields
+	Subject  pkix.Name
+	DNSNames []string
+	// ExtraExtensions are requested verbatim; Extensions holds all the
+	// requested extensions of a parsed request.
+	ExtraExtensions []pkix.Extension
+	Extensions      []pkix.Extension
+}
+
+// CreateCertificateRequest creates a new certificate request based on a
+// template, and returns its DER encoding. The request carries the public key
+// of priv, which must be a *oqs.SigPrivateKey or a *oqs.CompositePrivateKey,
+// and is signed by it as a proof of possession.
+func CreateCertificateRequest(template *CertificateRequest,
+	priv crypto.Signer,
+) ([]byte, error) {
+	_, sigAlg, err := signatureAlgorithm(priv)
+	if err != nil {
+		return nil, err
+	}
+	spki, err := oqs.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	subject, err := marshalName(template.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var exts []pkix.Extension
+	if len(template.DNSNames) > 0 {
+		ext, err := marshalSubjectAltName(template.DNSNames)
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, ext)
+	}
+	exts = append(exts, template.ExtraExtensions...)
+	var attributes []asn1.RawValue
+	if len(exts) > 0 {
+		value, err := asn1.Marshal(exts)
+		if err != nil {
+			return nil, err
+		}
+		attr, err := asn1.Marshal(attribute{
+			Type:   oidExtensionRequest,
+			Values: []asn1.RawValue{{FullBytes: value}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		attributes = append(attributes, asn1.RawValue{FullBytes: attr})
+	}
+
+	tbs, err := asn1.Marshal(tbsCertificateRequest{
+		Subject:       asn1.RawValue{FullBytes: subject},
+		PublicKey:     asn1.RawValue{FullBytes: spki},
+		RawAttributes: attributes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := priv.Sign(oqs.Rand, tbs, nil)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(certificateRequest{
+		TBSCertificateRequest: asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm:    sigAlg,
+		SignatureValue: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	})
+}
+
+// ParseCertificateRequest parses a single DER-encoded certificate request.
+// The signature is not checked, see CertificateRequest.CheckSignature.
+func ParseCertificateRequest(der []byte) (*CertificateRequest, error) {
+	var req certificateRequest
+	if rest, err := asn1.Unmarshal(der, &req); err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	var tbs tbsCertificateRequest
+	if rest, err := asn1.Unmarshal(req.TBSCertificateRequest.FullBytes,
+		&tbs); err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	sigAlgName, err := oqs.AlgorithmName(req.SignatureAlgorithm.Algorithm)
+	if err != nil {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	pub, err := oqs.ParsePKIXPublicKey(tbs.PublicKey.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	r := &CertificateRequest{
+		Raw:                      der,
+		RawTBSCertificateRequest: req.TBSCertificateRequest.FullBytes,
+		RawSubjectPublicKeyInfo:  tbs.PublicKey.FullBytes,
+		RawSubject:               tbs.Subject.FullBytes,
+		Signature:                req.SignatureValue.RightAlign(),
+		SignatureAlgorithm:       sigAlgName,
+		PublicKeyAlgorithm:       pub.(publicKeyAlgorithm).Algorithm(),
+		PublicKey:                pub,
+		Version:                  tbs.Version,
+	}
+	if r.Subject, err = parseName(r.RawSubject); err != nil {
+		return nil, err
+	}
+	for _, raw := range tbs.RawAttributes {
+		var attr attribute
+		if rest, err := asn1.Unmarshal(raw.FullBytes, &attr); err != nil ||
+			len(rest) != 0 {
+			return nil, ErrMalformed
+		}
+		if !attr.Type.Equal(oidExtensionRequest) || len(attr.Values) != 1 {
+			continue
+		}
+		if rest, err := asn1.Unmarshal(attr.Values[0].FullBytes,
+			&r.Extensions); err != nil || len(rest) != 0 {
+			return nil, ErrMalformed
+		}
+	}
+	for _, ext := range r.Extensions {
+		if ext.Id.Equal(oidExtensionSubjectAltName) {
+			if r.DNSNames, err = parseSubjectAltName(ext.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return r, nil
+}
+
+// CheckSignature reports whether the signature on r is valid, i.e. whether
+// the requester possesses the private key matching r.PublicKey.
+func (r *CertificateRequest) CheckSignature() error {
+	return checkSignature(r.SignatureAlgorithm, r.RawTBSCertificateRequest,
+		r.Signature, r.PublicKey)
+}
+
+/**************** END Certificate requests ****************/
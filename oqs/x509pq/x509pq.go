@@ -0,0 +1,764 @@
+// Package x509pq creates, parses and verifies X.509 certificates and
+// certificate requests signed with the quantum-resistant signatures of the
+// oqs package (ML-DSA, SLH-DSA, Falcon and composite signatures), which
+// crypto/x509 does not support.
+package x509pq // import "github.com/open-quantum-safe/liboqs-go/oqs/x509pq"
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+/**************** Errors ****************/
+
+var (
+	// ErrMalformed means that a certificate or a request can not be parsed.
+	ErrMalformed = errors.New("x509pq: malformed certificate or request")
+	// ErrUnsupportedAlgorithm means that a key or signature algorithm is not
+	// supported.
+	ErrUnsupportedAlgorithm = errors.New("x509pq: unsupported algorithm")
+	// ErrInvalidSignature means that a signature is not valid.
+	ErrInvalidSignature = errors.New("x509pq: invalid signature")
+	// ErrUnknownAuthority means that no chain to a trusted root was found.
+	ErrUnknownAuthority = errors.New("x509pq: certificate signed by " +
+		"unknown authority")
+	// ErrExpired means that a certificate of the chain is not valid at the
+	// verification time.
+	ErrExpired = errors.New("x509pq: certificate has expired or is not " +
+		"yet valid")
+	// ErrNotAuthorizedToSign means that an issuer is not a CA, or is not
+	// allowed to sign certificates.
+	ErrNotAuthorizedToSign = errors.New("x509pq: certificate is not " +
+		"authorized to sign other certificates")
+	// ErrTooManyIntermediates means that a path length constraint is
+	// violated.
+	ErrTooManyIntermediates = errors.New("x509pq: too many intermediates " +
+		"for path length constraint")
+	// ErrUnhandledCriticalExtension means that a certificate of the chain
+	// has a critical extension that this package does not process, e.g. name
+	// constraints.
+	ErrUnhandledCriticalExtension = errors.New("x509pq: unhandled critical " +
+		"extension")
+	// ErrIncompatibleUsage means that the extended key usages of a
+	// certificate of the chain do not permit any of the requested usages.
+	ErrIncompatibleUsage = errors.New("x509pq: certificate specifies an " +
+		"incompatible key usage")
+)
+
+/**************** END Errors ****************/
+
+/**************** Algorithms ****************/
+
+// publicKeyAlgorithm is implemented by the public key types of the oqs
+// package.
+type publicKeyAlgorithm interface {
+	Algorithm() string
+}
+
+// signatureVerifier is implemented by the signature public key types of the
+// oqs package.
+type signatureVerifier interface {
+	publicKeyAlgorithm
+	Verify(message, signature []byte, opts crypto.SignerOpts) (bool, error)
+}
+
+// signatureAlgorithm returns the signature AlgorithmIdentifier of a signer.
+// As for ML-DSA and SLH-DSA in X.509, it coincides with the public key
+// algorithm and has absent parameters.
+func signatureAlgorithm(signer crypto.Signer) (string,
+	pkix.AlgorithmIdentifier, error,
+) {
+	pub, ok := signer.Public().(signatureVerifier)
+	if !ok {
+		return "", pkix.AlgorithmIdentifier{}, ErrUnsupportedAlgorithm
+	}
+	oid, err := oqs.AlgorithmOID(pub.Algorithm())
+	if err != nil {
+		return "", pkix.AlgorithmIdentifier{}, err
+	}
+	return pub.Algorithm(), pkix.AlgorithmIdentifier{Algorithm: oid}, nil
+}
+
+// checkSignature verifies a signature made with the algorithm algName under
+// the public key pub.
+func checkSignature(algName string, signed, signature []byte,
+	pub crypto.PublicKey,
+) error {
+	verifier, ok := pub.(signatureVerifier)
+	if !ok || verifier.Algorithm() != algName {
+		return ErrUnsupportedAlgorithm
+	}
+	isValid, err := verifier.Verify(signed, signature, nil)
+	if err != nil {
+		return err
+	}
+	if !isValid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+/**************** END Algorithms ****************/
+
+/**************** Certificates ****************/
+
+// OIDs of the extensions handled by this package.
+var (
+	oidExtensionSubjectKeyID     = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidExtensionKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtensionSubjectAltName   = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtensionBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidExtensionAuthorityKeyID   = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtensionExtKeyUsage      = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtensionRequest          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+)
+
+const (
+	certificateVersion3       = 2  // encoded version of X.509 v3
+	generalNameDNSTag         = 2  // dNSName [2] IA5String
+	basicConstraintsNoPathLen = -1 // absent pathLenConstraint
+	keyUsageBits              = 9  // digitalSignature to decipherOnly
+	maxIntermediates          = 16 // bound on the chains built by Verify
+)
+
+// extKeyUsageOIDs maps the extended key usages supported by this package to
+// their OIDs.
+var extKeyUsageOIDs = []struct {
+	usage x509.ExtKeyUsage
+	oid   asn1.ObjectIdentifier
+}{
+	{x509.ExtKeyUsageAny, asn1.ObjectIdentifier{2, 5, 29, 37, 0}},
+	{x509.ExtKeyUsageServerAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}},
+	{x509.ExtKeyUsageClientAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+	{x509.ExtKeyUsageCodeSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}},
+	{x509.ExtKeyUsageEmailProtection, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}},
+	{x509.ExtKeyUsageTimeStamping, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}},
+	{x509.ExtKeyUsageOCSPSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}},
+}
+
+// certificate is the ASN.1 structure of an X.509 certificate.
+type certificate struct {
+	TBSCertificate     asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// tbsCertificate is the ASN.1 structure of an X.509 TBSCertificate.
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validity
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	UniqueID           asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+// validity is the ASN.1 structure of the validity period of a certificate.
+type validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+// basicConstraints is the ASN.1 structure of the basic constraints
+// extension.
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+// authorityKeyID is the ASN.1 structure of the authority key identifier
+// extension.
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+// Certificate is a parsed X.509 certificate whose public key and signature
+// use the algorithms of the oqs package. It also serves as a template for
+// CreateCertificate, in which case only the fields documented as template
+// fields are used.
+type Certificate struct {
+	Raw                     []byte // complete ASN.1 DER content
+	RawTBSCertificate       []byte // certificate part of Raw
+	RawSubjectPublicKeyInfo []byte // DER encoded SubjectPublicKeyInfo
+	RawSubject              []byte // DER encoded subject
+	RawIssuer               []byte // DER encoded issuer
+
+	Signature          []byte
+	SignatureAlgorithm string // oqs algorithm name of the signature
+
+	PublicKeyAlgorithm string // oqs algorithm name of the public key
+	// PublicKey is a *oqs.SigPublicKey, *oqs.CompositePublicKey or
+	// *oqs.KEMPublicKey.
+	PublicKey crypto.PublicKey
+
+	Version int
+
+	// Template fields
+	SerialNumber          *big.Int
+	Subject               pkix.Name
+	Issuer                pkix.Name // ignored in templates, set from parent
+	NotBefore, NotAfter   time.Time
+	KeyUsage              x509.KeyUsage
+	ExtKeyUsage           []x509.ExtKeyUsage
+	UnknownExtKeyUsage    []asn1.ObjectIdentifier
+	BasicConstraintsValid bool
+	IsCA                  bool
+	// MaxPathLen is the path length constraint of a CA; -1 or 0 together
+	// with MaxPathLenZero false means unconstrained.
+	MaxPathLen     int
+	MaxPathLenZero bool
+	SubjectKeyId   []byte
+	AuthorityKeyId []byte // ignored in templates, set from parent
+	DNSNames       []string
+	// ExtraExtensions are added verbatim to the certificate; Extensions
+	// holds all the extensions of a parsed certificate.
+	ExtraExtensions []pkix.Extension
+	Extensions      []pkix.Extension
+	// UnhandledCriticalExtensions lists the critical extensions of a parsed
+	// certificate that this package does not process; Verify rejects such
+	// certificates, as required by RFC 5280, section 4.2.
+	UnhandledCriticalExtensions []asn1.ObjectIdentifier
+}
+
+// marshalName returns the DER encoding of a distinguished name.
+func marshalName(name pkix.Name) ([]byte, error) {
+	return asn1.Marshal(name.ToRDNSequence())
+}
+
+// parseName parses a DER-encoded distinguished name.
+func parseName(der []byte) (pkix.Name, error) {
+	var rdn pkix.RDNSequence
+	var name pkix.Name
+	if rest, err := asn1.Unmarshal(der, &rdn); err != nil || len(rest) != 0 {
+		return name, ErrMalformed
+	}
+	name.FillFromRDNSequence(&rdn)
+	return name, nil
+}
+
+// reverseBits reverses the bits of a byte, see RFC 5280 for the encoding of
+// the key usage BIT STRING.
+func reverseBits(b byte) byte {
+	var r byte
+	for i := 0; i < 8; i++ {
+		r = r<<1 | b&1
+		b >>= 1
+	}
+	return r
+}
+
+// marshalKeyUsage encodes the key usage extension.
+func marshalKeyUsage(ku x509.KeyUsage) (pkix.Extension, error) {
+	a := []byte{reverseBits(byte(ku)), reverseBits(byte(ku >> 8))}
+	if a[1] == 0 {
+		a = a[:1]
+	}
+	bitLength := len(a) * 8
+	for i := 0; i < 8 && a[len(a)-1]&(1<<i) == 0; i++ {
+		bitLength--
+	}
+	value, err := asn1.Marshal(asn1.BitString{Bytes: a, BitLength: bitLength})
+	return pkix.Extension{Id: oidExtensionKeyUsage, Critical: true,
+		Value: value}, err
+}
+
+// marshalSubjectAltName encodes the subject alternative name extension.
+func marshalSubjectAltName(dnsNames []string) (pkix.Extension, error) {
+	var names []asn1.RawValue
+	for _, name := range dnsNames {
+		names = append(names, asn1.RawValue{Tag: generalNameDNSTag,
+			Class: asn1.ClassContextSpecific, Bytes: []byte(name)})
+	}
+	value, err := asn1.Marshal(names)
+	return pkix.Extension{Id: oidExtensionSubjectAltName, Value: value}, err
+}
+
+// parseSubjectAltName decodes the DNS names of the subject alternative name
+// extension.
+func parseSubjectAltName(value []byte) ([]string, error) {
+	var names []asn1.RawValue
+	if rest, err := asn1.Unmarshal(value, &names); err != nil ||
+		len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	var dnsNames []string
+	for _, name := range names {
+		if name.Class == asn1.ClassContextSpecific &&
+			name.Tag == generalNameDNSTag {
+			dnsNames = append(dnsNames, string(name.Bytes))
+		}
+	}
+	return dnsNames, nil
+}
+
+// marshalExtKeyUsage encodes the extended key usage extension.
+func marshalExtKeyUsage(usages []x509.ExtKeyUsage,
+	unknown []asn1.ObjectIdentifier,
+) (pkix.Extension, error) {
+	oids := append([]asn1.ObjectIdentifier(nil), unknown...)
+	for _, usage := range usages {
+		n := len(oids)
+		for _, e := range extKeyUsageOIDs {
+			if e.usage == usage {
+				oids = append(oids, e.oid)
+				break
+			}
+		}
+		if len(oids) == n {
+			return pkix.Extension{}, errors.New("x509pq: unsupported " +
+				"extended key usage")
+		}
+	}
+	value, err := asn1.Marshal(oids)
+	return pkix.Extension{Id: oidExtensionExtKeyUsage, Value: value}, err
+}
+
+// parseExtKeyUsage decodes the extended key usage extension into c.
+func (c *Certificate) parseExtKeyUsage(value []byte) error {
+	var oids []asn1.ObjectIdentifier
+	if rest, err := asn1.Unmarshal(value, &oids); err != nil ||
+		len(rest) != 0 {
+		return ErrMalformed
+	}
+	for _, oid := range oids {
+		known := false
+		for _, e := range extKeyUsageOIDs {
+			if e.oid.Equal(oid) {
+				c.ExtKeyUsage = append(c.ExtKeyUsage, e.usage)
+				known = true
+				break
+			}
+		}
+		if !known {
+			c.UnknownExtKeyUsage = append(c.UnknownExtKeyUsage, oid)
+		}
+	}
+	return nil
+}
+
+// buildExtensions encodes the extensions of a certificate template.
+func buildExtensions(template *Certificate, subjectKeyID,
+	authorityKeyIDValue []byte,
+) ([]pkix.Extension, error) {
+	var exts []pkix.Extension
+	if template.BasicConstraintsValid {
+		maxPathLen := template.MaxPathLen
+		if maxPathLen == 0 && !template.MaxPathLenZero {
+			maxPathLen = basicConstraintsNoPathLen
+		}
+		value, err := asn1.Marshal(basicConstraints{template.IsCA, maxPathLen})
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtensionBasicConstraints,
+			Critical: true, Value: value})
+	}
+	if template.KeyUsage != 0 {
+		ext, err := marshalKeyUsage(template.KeyUsage)
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, ext)
+	}
+	if len(subjectKeyID) > 0 {
+		value, err := asn1.Marshal(subjectKeyID)
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtensionSubjectKeyID,
+			Value: value})
+	}
+	if len(authorityKeyIDValue) > 0 {
+		value, err := asn1.Marshal(authorityKeyID{authorityKeyIDValue})
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtensionAuthorityKeyID,
+			Value: value})
+	}
+	if len(template.ExtKeyUsage) > 0 || len(template.UnknownExtKeyUsage) > 0 {
+		ext, err := marshalExtKeyUsage(template.ExtKeyUsage,
+			template.UnknownExtKeyUsage)
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, ext)
+	}
+	if len(template.DNSNames) > 0 {
+		ext, err := marshalSubjectAltName(template.DNSNames)
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, ext)
+	}
+	return append(exts, template.ExtraExtensions...), nil
+}
+
+// CreateCertificate creates a new X.509 v3 certificate based on a template,
+// and returns its DER encoding. The certificate is signed by priv, which must
+// be a *oqs.SigPrivateKey or a *oqs.CompositePrivateKey, and whose public key
+// must belong to parent; if parent is equal to template, the certificate is
+// self-signed. pub is the public key of the subject, i.e. a
+// *oqs.SigPublicKey, a *oqs.CompositePublicKey or a *oqs.KEMPublicKey.
+func CreateCertificate(template, parent *Certificate, pub crypto.PublicKey,
+	priv crypto.Signer,
+) ([]byte, error) {
+	if template.SerialNumber == nil {
+		return nil, errors.New("x509pq: no SerialNumber given")
+	}
+	sigAlgName, sigAlg, err := signatureAlgorithm(priv)
+	if err != nil {
+		return nil, err
+	}
+	spki, err := oqs.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	subject, err := marshalName(template.Subject)
+	if err != nil {
+		return nil, err
+	}
+	issuer := subject
+	if parent != template {
+		if len(parent.RawSubject) > 0 {
+			issuer = parent.RawSubject
+		} else if issuer, err = marshalName(parent.Subject); err != nil {
+			return nil, err
+		}
+	}
+
+	subjectKeyID := template.SubjectKeyId
+	if len(subjectKeyID) == 0 && template.IsCA {
+		var keyBits struct {
+			Algorithm pkix.AlgorithmIdentifier
+			PublicKey asn1.BitString
+		}
+		if _, err := asn1.Unmarshal(spki, &keyBits); err != nil {
+			return nil, err
+		}
+		sum := sha1.Sum(keyBits.PublicKey.Bytes)
+		subjectKeyID = sum[:]
+	}
+	authorityKeyIDValue := parent.SubjectKeyId
+	if parent == template {
+		authorityKeyIDValue = subjectKeyID
+	}
+	exts, err := buildExtensions(template, subjectKeyID, authorityKeyIDValue)
+	if err != nil {
+		return nil, err
+	}
+
+	tbs, err := asn1.Marshal(tbsCertificate{
+		Version:            certificateVersion3,
+		SerialNumber:       template.SerialNumber,
+		SignatureAlgorithm: sigAlg,
+		Issuer:             asn1.RawValue{FullBytes: issuer},
+		Validity:           validity{template.NotBefore.UTC(), template.NotAfter.UTC()},
+		Subject:            asn1.RawValue{FullBytes: subject},
+		PublicKey:          asn1.RawValue{FullBytes: spki},
+		Extensions:         exts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := priv.Sign(oqs.Rand, tbs, nil)
+	if err != nil {
+		return nil, err
+	}
+	if parent == template || parent.PublicKey != nil {
+		issuerKey := parent.PublicKey
+		if parent == template {
+			issuerKey = pub
+		}
+		if err := checkSignature(sigAlgName, tbs, signature,
+			issuerKey); err != nil {
+			return nil, errors.New("x509pq: signing key does not " +
+				"match the parent public key")
+		}
+	}
+	return asn1.Marshal(certificate{
+		TBSCertificate:     asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: sigAlg,
+		SignatureValue: asn1.BitString{
+			Bytes:     signature,
+			BitLength: 8 * len(signature),
+		},
+	})
+}
+
+// ParseCertificate parses a single DER-encoded certificate.
+func ParseCertificate(der []byte) (*Certificate, error) {
+	var cert certificate
+	if rest, err := asn1.Unmarshal(der, &cert); err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	var tbs tbsCertificate
+	if rest, err := asn1.Unmarshal(cert.TBSCertificate.FullBytes,
+		&tbs); err != nil || len(rest) != 0 {
+		return nil, ErrMalformed
+	}
+	if !tbs.SignatureAlgorithm.Algorithm.Equal(
+		cert.SignatureAlgorithm.Algorithm) {
+		return nil, ErrMalformed
+	}
+	sigAlgName, err := oqs.AlgorithmName(cert.SignatureAlgorithm.Algorithm)
+	if err != nil {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	pub, err := oqs.ParsePKIXPublicKey(tbs.PublicKey.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	c := &Certificate{
+		Raw:                     der,
+		RawTBSCertificate:       cert.TBSCertificate.FullBytes,
+		RawSubjectPublicKeyInfo: tbs.PublicKey.FullBytes,
+		RawSubject:              tbs.Subject.FullBytes,
+		RawIssuer:               tbs.Issuer.FullBytes,
+		Signature:               cert.SignatureValue.RightAlign(),
+		SignatureAlgorithm:      sigAlgName,
+		PublicKeyAlgorithm:      pub.(publicKeyAlgorithm).Algorithm(),
+		PublicKey:               pub,
+		Version:                 tbs.Version + 1,
+		SerialNumber:            tbs.SerialNumber,
+		NotBefore:               tbs.Validity.NotBefore,
+		NotAfter:                tbs.Validity.NotAfter,
+		Extensions:              tbs.Extensions,
+	}
+	if c.Subject, err = parseName(c.RawSubject); err != nil {
+		return nil, err
+	}
+	if c.Issuer, err = parseName(c.RawIssuer); err != nil {
+		return nil, err
+	}
+	if err := c.parseExtensions(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseExtensions fills in the fields of c that derive from its extensions,
+// and records the critical extensions it does not process.
+func (c *Certificate) parseExtensions() error {
+	for _, ext := range c.Extensions {
+		var err error
+		var rest []byte
+		switch {
+		case ext.Id.Equal(oidExtensionBasicConstraints):
+			var constraints basicConstraints
+			rest, err = asn1.Unmarshal(ext.Value, &constraints)
+			c.BasicConstraintsValid = true
+			c.IsCA = constraints.IsCA
+			c.MaxPathLen = constraints.MaxPathLen
+			c.MaxPathLenZero = c.MaxPathLen == 0
+		case ext.Id.Equal(oidExtensionKeyUsage):
+			var usage asn1.BitString
+			rest, err = asn1.Unmarshal(ext.Value, &usage)
+			for i := 0; i < keyUsageBits; i++ {
+				if usage.At(i) != 0 {
+					c.KeyUsage |= 1 << uint(i)
+				}
+			}
+		case ext.Id.Equal(oidExtensionSubjectKeyID):
+			rest, err = asn1.Unmarshal(ext.Value, &c.SubjectKeyId)
+		case ext.Id.Equal(oidExtensionAuthorityKeyID):
+			var id authorityKeyID
+			rest, err = asn1.Unmarshal(ext.Value, &id)
+			c.AuthorityKeyId = id.ID
+		case ext.Id.Equal(oidExtensionSubjectAltName):
+			c.DNSNames, err = parseSubjectAltName(ext.Value)
+		case ext.Id.Equal(oidExtensionExtKeyUsage):
+			err = c.parseExtKeyUsage(ext.Value)
+		default:
+			if ext.Critical {
+				c.UnhandledCriticalExtensions = append(
+					c.UnhandledCriticalExtensions, ext.Id)
+			}
+		}
+		if err != nil || len(rest) != 0 {
+			return ErrMalformed
+		}
+	}
+	return nil
+}
+
+// Equal reports whether c and other are the same certificate.
+func (c *Certificate) Equal(other *Certificate) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return bytes.Equal(c.Raw, other.Raw)
+}
+
+// CheckSignatureFrom verifies that the signature on c is a valid signature
+// from parent.
+func (c *Certificate) CheckSignatureFrom(parent *Certificate) error {
+	if parent.Version == 3 && !parent.BasicConstraintsValid ||
+		parent.BasicConstraintsValid && !parent.IsCA {
+		return ErrNotAuthorizedToSign
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return ErrNotAuthorizedToSign
+	}
+	return checkSignature(c.SignatureAlgorithm, c.RawTBSCertificate,
+		c.Signature, parent.PublicKey)
+}
+
+/**************** END Certificates ****************/
+
+/**************** Chain verification ****************/
+
+// VerifyOptions contains the parameters of Certificate.Verify.
+type VerifyOptions struct {
+	// Roots are the trusted root certificates.
+	Roots []*Certificate
+	// Intermediates are untrusted certificates that may be used to build
+	// a chain from the leaf to a root.
+	Intermediates []*Certificate
+	// CurrentTime is the verification time; time.Now() is used if zero.
+	CurrentTime time.Time
+	// KeyUsages are the acceptable extended key usages; as in crypto/x509,
+	// x509.ExtKeyUsageServerAuth is used if empty, and x509.ExtKeyUsageAny
+	// accepts any usage.
+	KeyUsages []x509.ExtKeyUsage
+}
+
+// Verify attempts to build a chain from c to one of opts.Roots, possibly via
+// some of opts.Intermediates, and returns it, starting with c and ending with
+// the root; if c is itself one of opts.Roots, the chain is c alone. Each
+// certificate of the chain must be valid at the verification time, have no
+// unhandled critical extension and, if it has extended key usages, permit one
+// of opts.KeyUsages. Each issuer must be a CA allowed to sign certificates,
+// path length constraints must be respected, and every signature must
+// verify.
+func (c *Certificate) Verify(opts VerifyOptions) ([]*Certificate, error) {
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if len(opts.KeyUsages) == 0 {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	if err := checkCertificate(c, &opts, now); err != nil {
+		return nil, err
+	}
+	for _, root := range opts.Roots {
+		if root.Equal(c) {
+			return []*Certificate{c}, nil
+		}
+	}
+	return buildChain([]*Certificate{c}, &opts, now)
+}
+
+// checkCertificate performs the checks that apply to every certificate of a
+// chain: its validity period, its critical extensions and its extended key
+// usages.
+func checkCertificate(c *Certificate, opts *VerifyOptions,
+	now time.Time,
+) error {
+	if now.Before(c.NotBefore) || now.After(c.NotAfter) {
+		return ErrExpired
+	}
+	if len(c.UnhandledCriticalExtensions) > 0 {
+		return ErrUnhandledCriticalExtension
+	}
+	if !c.permitsUsage(opts.KeyUsages) {
+		return ErrIncompatibleUsage
+	}
+	return nil
+}
+
+// permitsUsage reports whether the extended key usages of c, if any, permit
+// one of usages.
+func (c *Certificate) permitsUsage(usages []x509.ExtKeyUsage) bool {
+	if len(c.ExtKeyUsage) == 0 && len(c.UnknownExtKeyUsage) == 0 ||
+		slices.Contains(c.ExtKeyUsage, x509.ExtKeyUsageAny) ||
+		slices.Contains(usages, x509.ExtKeyUsageAny) {
+		return true
+	}
+	for _, usage := range usages {
+		if slices.Contains(c.ExtKeyUsage, usage) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildChain extends chain towards a root, trying roots first and then
+// intermediates, and returns the first complete chain found.
+func buildChain(chain []*Certificate, opts *VerifyOptions,
+	now time.Time,
+) ([]*Certificate, error) {
+	current := chain[len(chain)-1]
+	err := ErrUnknownAuthority
+	try := func(issuer *Certificate, isRoot bool) []*Certificate {
+		if !bytes.Equal(current.RawIssuer, issuer.RawSubject) {
+			return nil
+		}
+		for _, cert := range chain {
+			if cert.Equal(issuer) {
+				return nil
+			}
+		}
+		if e := checkIssuer(current, issuer, len(chain)-1, opts,
+			now); e != nil {
+			err = e
+			return nil
+		}
+		next := append(append([]*Certificate(nil), chain...), issuer)
+		if isRoot {
+			return next
+		}
+		if len(next) > maxIntermediates {
+			err = ErrTooManyIntermediates
+			return nil
+		}
+		found, e := buildChain(next, opts, now)
+		if e != nil {
+			err = e
+		}
+		return found
+	}
+	for _, root := range opts.Roots {
+		if found := try(root, true); found != nil {
+			return found, nil
+		}
+	}
+	for _, intermediate := range opts.Intermediates {
+		if found := try(intermediate, false); found != nil {
+			return found, nil
+		}
+	}
+	return nil, err
+}
+
+// checkIssuer checks that issuer may sign cert, where intermediates is the
+// number of intermediate certificates between issuer and the leaf.
+func checkIssuer(cert, issuer *Certificate, intermediates int,
+	opts *VerifyOptions, now time.Time,
+) error {
+	if err := checkCertificate(issuer, opts, now); err != nil {
+		return err
+	}
+	if issuer.BasicConstraintsValid && issuer.MaxPathLen >= 0 &&
+		(issuer.MaxPathLen > 0 || issuer.MaxPathLenZero) &&
+		intermediates > issuer.MaxPathLen {
+		return ErrTooManyIntermediates
+	}
+	return cert.CheckSignatureFrom(issuer)
+}
+
+/**************** END Chain verification ****************/
@@ -0,0 +1,100 @@
+package oqstests
+
+import (
+	"context"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestVerifyBatch tests the batch verification of all enabled signatures.
+func TestVerifyBatch(t *testing.T) {
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("Batch - ", sigName)
+		signer, _ := oqs.NewSignature(sigName)
+		publicKey, _ := signer.GenerateKeyPair()
+
+		items := make([]oqs.VerifyItem, 20)
+		for i := range items {
+			msg := []byte{byte(i)}
+			signature, err := signer.Sign(msg)
+			if err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+			items[i] = oqs.VerifyItem{Message: msg, Signature: signature, PublicKey: publicKey}
+		}
+		items[3].Message = []byte("tampered")
+		items[7].PublicKey = publicKey[1:]
+		if signer.Details().SigWithCtxSupport {
+			signature, _ := signer.SignWithCtxStr(items[9].Message, []byte("context"))
+			items[9].Signature, items[9].Context = signature, []byte("context")
+		}
+
+		verifier, _ := oqs.NewSignature(sigName)
+		results, stats := verifier.VerifyBatchContext(context.Background(), items)
+		for i, err := range results {
+			switch i {
+			case 3:
+				if !errors.Is(err, oqs.ErrVerificationFailed) {
+					t.Errorf("%s: item %d: expected ErrVerificationFailed, got %v", sigName, i, err)
+				}
+			case 7:
+				if !errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+					t.Errorf("%s: item %d: expected ErrInvalidPublicKeyLength, got %v", sigName, i, err)
+				}
+			default:
+				if err != nil {
+					t.Errorf("%s: item %d: %v", sigName, i, err)
+				}
+			}
+		}
+		if stats.Items != len(items) || stats.Valid != len(items)-2 || stats.Workers == 0 {
+			t.Errorf("%s: unexpected statistics %+v", sigName, stats)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		for _, err := range verifier.VerifyBatch(nil) {
+			t.Errorf("%s: unexpected result %v", sigName, err)
+		}
+		results, stats = verifier.VerifyBatchContext(ctx, items)
+		if stats.Items != 0 || !errors.Is(results[0], context.Canceled) {
+			t.Errorf("%s: cancelled batch was fully verified", sigName)
+		}
+		verifier.Clean()
+		signer.Clean()
+	}
+}
+
+// BenchmarkVerifyBatch compares the batch verification of ML-DSA-65 with
+// sequential Verify calls.
+func BenchmarkVerifyBatch(b *testing.B) {
+	const sigName = "ML-DSA-65"
+	signer, err := oqs.NewSignature(sigName)
+	if err != nil {
+		b.Skip(err)
+	}
+	defer signer.Clean()
+	publicKey, _ := signer.GenerateKeyPair()
+	items := make([]oqs.VerifyItem, 256)
+	for i := range items {
+		msg := []byte{byte(i)}
+		signature, _ := signer.Sign(msg)
+		items[i] = oqs.VerifyItem{Message: msg, Signature: signature, PublicKey: publicKey}
+	}
+
+	b.Run("Sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, item := range items {
+				_, _ = signer.Verify(item.Message, item.Signature, item.PublicKey)
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			signer.VerifyBatch(items)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+package oqstests
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestCompositeSignature tests all composite signature algorithms whose
+// ML-DSA component is enabled.
+func TestCompositeSignature(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	ctx := &oqs.SignerOpts{Context: []byte("context")}
+	for _, algName := range oqs.SupportedCompositeSigs() {
+		priv, err := oqs.GenerateCompositeKey(algName)
+		if errors.Is(err, oqs.ErrAlgorithmNotEnabled) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", algName, err)
+		}
+		log.Println("Composite correctness - ", algName)
+		signature, err := priv.Sign(nil, msg, ctx)
+		if err != nil {
+			t.Fatalf("%s: %v", algName, err)
+		}
+
+		// Round-trip the keys through their DER encodings
+		pubDER, _ := priv.Public().(*oqs.CompositePublicKey).Bytes()
+		pub, err := oqs.ParseCompositePublicKey(algName, pubDER)
+		if err != nil {
+			t.Fatalf("%s: %v", algName, err)
+		}
+		privDER, err := priv.Bytes()
+		if err != nil {
+			t.Fatalf("%s: %v", algName, err)
+		}
+		parsed, err := oqs.ParseCompositePrivateKey(algName, privDER)
+		if err != nil {
+			t.Fatalf("%s: %v", algName, err)
+		}
+		if !parsed.Public().(*oqs.CompositePublicKey).Equal(pub) {
+			t.Errorf("%s: parsed public keys do not coincide", algName)
+		}
+
+		if isValid, _ := pub.Verify(msg, signature, ctx); !isValid {
+			t.Errorf("%s: signature verification failed", algName)
+		}
+		if isValid, _ := pub.Verify(msg, signature, nil); isValid {
+			t.Errorf("%s: signature verification with wrong context should have failed", algName)
+		}
+		signature, _ = parsed.Sign(nil, msg, nil)
+		if isValid, _ := priv.Verify(msg, signature, nil); !isValid {
+			t.Errorf("%s: signature verification with parsed key failed", algName)
+		}
+
+		// Both component signatures must be valid
+		for _, i := range []int{len(signature) / 4, len(signature) - 4} {
+			wrongSignature := append([]byte(nil), signature...)
+			wrongSignature[i] ^= 0x01
+			if isValid, _ := pub.Verify(msg, wrongSignature, nil); isValid {
+				t.Errorf("%s: signature verification should have failed", algName)
+			}
+		}
+		parsed.Clean()
+		priv.Clean()
+	}
+}
+
+// TestCompositeSignatureFormat checks the composite encodings and message
+// representative of draft-ietf-lamps-pq-composite-sigs-12 against the
+// component algorithms.
+func TestCompositeSignatureFormat(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	ctx := []byte("context")
+	for _, tc := range []struct {
+		algName, mldsa string
+		prehash        func([]byte) []byte
+	}{
+		{"MLDSA44-Ed25519-SHA512", "ML-DSA-44", func(m []byte) []byte { h := sha512.Sum512(m); return h[:] }},
+		{"MLDSA44-ECDSA-P256-SHA256", "ML-DSA-44", func(m []byte) []byte { h := sha256.Sum256(m); return h[:] }},
+	} {
+		priv, err := oqs.GenerateCompositeKey(tc.algName)
+		if errors.Is(err, oqs.ErrAlgorithmNotEnabled) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.algName, err)
+		}
+		log.Println("Composite format - ", tc.algName)
+		signature, _ := priv.Sign(nil, msg, &oqs.SignerOpts{Context: ctx})
+		publicKey, _ := priv.Public().(*oqs.CompositePublicKey).Bytes()
+		secretKey, _ := priv.Bytes()
+
+		// M' = Prefix || Label || len(ctx) || ctx || PH(M)
+		label := []byte("COMPSIG-" + tc.algName)
+		m := append([]byte("CompositeAlgorithmSignatures2025"), label...)
+		m = append(m, byte(len(ctx)))
+		m = append(m, ctx...)
+		m = append(m, tc.prehash(msg)...)
+
+		// The ML-DSA component is expanded from the leading 32-byte seed.
+		verifier, _ := oqs.NewSignature(tc.mldsa)
+		details := verifier.Details()
+		mldsaPublicKey, _ := verifier.GenerateKeyPairFromSeed(secretKey[:32])
+		if !bytes.Equal(mldsaPublicKey, publicKey[:details.LengthPublicKey]) {
+			t.Errorf("%s: ML-DSA public keys do not coincide", tc.algName)
+		}
+		sigMLDSA, sigTrad := signature[:details.MaxLengthSignature], signature[details.MaxLengthSignature:]
+		if isValid, _ := verifier.VerifyWithCtxStr(m, sigMLDSA, label, mldsaPublicKey); !isValid {
+			t.Errorf("%s: ML-DSA component verification failed", tc.algName)
+		}
+		verifier.Clean()
+
+		tradPublicKey := publicKey[details.LengthPublicKey:]
+		if tc.algName == "MLDSA44-Ed25519-SHA512" {
+			if !ed25519.Verify(tradPublicKey, m, sigTrad) {
+				t.Errorf("%s: Ed25519 component verification failed", tc.algName)
+			}
+		} else {
+			ecKey, err := x509.ParseECPrivateKey(secretKey[32:])
+			if err != nil {
+				t.Fatalf("%s: %v", tc.algName, err)
+			}
+			digest := sha256.Sum256(m)
+			if !ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], sigTrad) {
+				t.Errorf("%s: ECDSA component verification failed", tc.algName)
+			}
+		}
+		priv.Clean()
+	}
+}
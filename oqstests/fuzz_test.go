@@ -0,0 +1,365 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// The fuzz targets below select the algorithm with their first argument,
+// modulo the number of enabled algorithms, so that a single target covers all
+// of them. Their seed corpora are generated from real key pairs, hence
+// "go test" runs them as regular tests, and "go test -fuzz" mutates them.
+
+// fuzzMessage is signed to build the seed corpora.
+var fuzzMessage = []byte("This is our favourite message to sign")
+
+// FuzzDecapSecret decapsulates mutated ciphertexts with mutated secret keys.
+// Decapsulation must never panic, must enforce the length contracts and, for
+// ML-KEM, must implicitly reject invalid ciphertexts under the original
+// secret key, i.e. succeed with a shared secret that differs from the
+// encapsulated one.
+func FuzzDecapSecret(f *testing.F) {
+	kemNames := oqs.EnabledKEMs()
+	if len(kemNames) == 0 {
+		f.Skip("no KEM is enabled")
+	}
+	type keyPair struct{ secretKey, ciphertext, sharedSecret []byte }
+	keyPairs := make([]keyPair, len(kemNames))
+	for i, kemName := range kemNames {
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		publicKey, err := kem.GenerateKeyPair()
+		if err != nil {
+			f.Fatalf("%s: %v", kemName, err)
+		}
+		ciphertext, sharedSecret, _ := kem.EncapSecret(publicKey)
+		keyPairs[i] = keyPair{
+			append([]byte(nil), kem.ExportSecretKey()...),
+			ciphertext, sharedSecret,
+		}
+		kem.Clean()
+
+		flipped := append([]byte(nil), ciphertext...)
+		flipped[len(flipped)/2] ^= 1
+		f.Add(uint8(i), ciphertext, keyPairs[i].secretKey)
+		f.Add(uint8(i), flipped, keyPairs[i].secretKey)
+		f.Add(uint8(i), ciphertext[1:], keyPairs[i].secretKey)
+	}
+
+	f.Fuzz(func(t *testing.T, alg uint8, ciphertext, secretKey []byte) {
+		i := int(alg) % len(kemNames)
+		kemName, original := kemNames[i], keyPairs[i]
+		// Clean cleanses the secret key, which must not modify the input
+		kem, err := oqs.NewKeyEncapsulation(kemName,
+			oqs.WithSecretKey(append([]byte(nil), secretKey...)))
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		defer kem.Clean()
+		details := kem.Details()
+
+		sharedSecret, err := kem.DecapSecret(ciphertext)
+		switch {
+		case len(ciphertext) != details.LengthCiphertext:
+			if !errors.Is(err, oqs.ErrInvalidCiphertextLength) {
+				t.Fatalf("%s: expected ErrInvalidCiphertextLength, got %v", kemName, err)
+			}
+			return
+		case len(secretKey) == 0:
+			if !errors.Is(err, oqs.ErrNoSecretKey) {
+				t.Fatalf("%s: expected ErrNoSecretKey, got %v", kemName, err)
+			}
+			return
+		case len(secretKey) != details.LengthSecretKey:
+			if !errors.Is(err, oqs.ErrInvalidSecretKeyLength) {
+				t.Fatalf("%s: expected ErrInvalidSecretKeyLength, got %v", kemName, err)
+			}
+			return
+		}
+		// FIPS 203 allows rejecting a mutated decapsulation key that fails
+		// the hash check, hence implicit rejection is only required for the
+		// original key
+		isOriginal := bytes.Equal(secretKey, original.secretKey)
+		if err != nil {
+			if isOriginal && strings.HasPrefix(kemName, "ML-KEM-") {
+				t.Fatalf("%s: implicit rejection expected, got %v", kemName, err)
+			}
+			return
+		}
+		if len(sharedSecret) != details.LengthSharedSecret {
+			t.Fatalf("%s: shared secret of %d bytes, expected %d", kemName,
+				len(sharedSecret), details.LengthSharedSecret)
+		}
+		if !isOriginal {
+			return
+		}
+		if bytes.Equal(ciphertext, original.ciphertext) !=
+			bytes.Equal(sharedSecret, original.sharedSecret) {
+			t.Fatalf("%s: decapsulated shared secret is inconsistent with the ciphertext", kemName)
+		}
+	})
+}
+
+// FuzzVerify verifies mutated messages, signatures, public keys and context
+// strings. Verification must never panic, must enforce the length contracts,
+// must accept the original signatures and must agree with VerifyBatch.
+func FuzzVerify(f *testing.F) {
+	sigNames := oqs.EnabledSigs()
+	if len(sigNames) == 0 {
+		f.Skip("no signature is enabled")
+	}
+	type signed struct{ context, signature, publicKey []byte }
+	originals := make([]signed, len(sigNames))
+	for i, sigName := range sigNames {
+		signer, _ := oqs.NewSignature(sigName)
+		publicKey, err := signer.GenerateKeyPair()
+		if err != nil {
+			f.Fatalf("%s: %v", sigName, err)
+		}
+		var context []byte
+		if signer.Details().SigWithCtxSupport {
+			context = []byte("context")
+		}
+		var signature []byte
+		if context != nil {
+			signature, err = signer.SignWithCtxStr(fuzzMessage, context)
+		} else {
+			signature, err = signer.Sign(fuzzMessage)
+		}
+		if err != nil {
+			f.Fatalf("%s: %v", sigName, err)
+		}
+		signer.Clean()
+		originals[i] = signed{context, signature, publicKey}
+
+		flipped := append([]byte(nil), signature...)
+		flipped[len(flipped)/2] ^= 1
+		f.Add(uint8(i), fuzzMessage, signature, publicKey, context)
+		f.Add(uint8(i), fuzzMessage, flipped, publicKey, context)
+		f.Add(uint8(i), fuzzMessage, signature, publicKey[1:], context)
+		f.Add(uint8(i), fuzzMessage, append(signature, 0), publicKey, []byte("other context"))
+	}
+
+	f.Fuzz(func(t *testing.T, alg uint8, message, signature, publicKey, context []byte) {
+		i := int(alg) % len(sigNames)
+		sigName, original := sigNames[i], originals[i]
+		verifier, err := oqs.NewSignature(sigName)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		defer verifier.Clean()
+		details := verifier.Details()
+
+		var isValid bool
+		if len(context) == 0 {
+			isValid, err = verifier.Verify(message, signature, publicKey)
+		} else {
+			isValid, err = verifier.VerifyWithCtxStr(message, signature, context, publicKey)
+		}
+		switch {
+		case len(context) > 0 && !details.SigWithCtxSupport:
+			if !errors.Is(err, oqs.ErrContextNotSupported) {
+				t.Fatalf("%s: expected ErrContextNotSupported, got %v", sigName, err)
+			}
+		case len(publicKey) != details.LengthPublicKey:
+			if !errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+				t.Fatalf("%s: expected ErrInvalidPublicKeyLength, got %v", sigName, err)
+			}
+		case len(signature) > details.MaxLengthSignature:
+			if !errors.Is(err, oqs.ErrInvalidSignatureLength) {
+				t.Fatalf("%s: expected ErrInvalidSignatureLength, got %v", sigName, err)
+			}
+		case err != nil:
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if bytes.Equal(message, fuzzMessage) &&
+			bytes.Equal(signature, original.signature) &&
+			bytes.Equal(publicKey, original.publicKey) &&
+			bytes.Equal(context, original.context) && !isValid {
+			t.Fatalf("%s: valid signature rejected", sigName)
+		}
+
+		item := oqs.VerifyItem{
+			Message:   message,
+			Signature: signature,
+			PublicKey: publicKey,
+			Context:   context,
+		}
+		if batchErr := verifier.VerifyBatch([]oqs.VerifyItem{item})[0]; (batchErr == nil) != isValid {
+			t.Fatalf("%s: VerifyBatch returned %v, Verify returned %v", sigName, batchErr, isValid)
+		}
+	})
+}
+
+// FuzzParsePKIXPublicKey parses mutated SubjectPublicKeyInfo encodings. A
+// parsed public key must re-encode to a DER that parses to the same key.
+func FuzzParsePKIXPublicKey(f *testing.F) {
+	for _, key := range fuzzPKIXKeys(f) {
+		der, err := oqs.MarshalPKIXPublicKey(key.public)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(der)
+		f.Add(der[:len(der)-1])
+		key.private.(interface{ Clean() }).Clean()
+	}
+
+	f.Fuzz(func(t *testing.T, der []byte) {
+		pub, err := oqs.ParsePKIXPublicKey(der)
+		if err != nil {
+			return
+		}
+		reencoded, err := oqs.MarshalPKIXPublicKey(pub)
+		if err != nil {
+			t.Fatalf("parsed public key does not re-encode: %v", err)
+		}
+		reparsed, err := oqs.ParsePKIXPublicKey(reencoded)
+		if err != nil {
+			t.Fatalf("re-encoded public key does not parse: %v", err)
+		}
+		if again, _ := oqs.MarshalPKIXPublicKey(reparsed); !bytes.Equal(again, reencoded) {
+			t.Fatalf("re-encoded public keys do not coincide")
+		}
+	})
+}
+
+// FuzzParsePKCS8PrivateKey parses mutated PKCS#8 encodings, in the expanded,
+// seed and both formats. A parsed private key must re-encode to a DER that
+// parses to the same key.
+func FuzzParsePKCS8PrivateKey(f *testing.F) {
+	for _, key := range fuzzPKIXKeys(f) {
+		for _, format := range []oqs.PrivateKeyFormat{
+			oqs.PrivateKeyExpanded, oqs.PrivateKeySeed, oqs.PrivateKeyBoth,
+		} {
+			der, err := oqs.MarshalPKCS8PrivateKeyFormat(key.private, format)
+			if errors.Is(err, oqs.ErrNoSeed) {
+				continue
+			} else if err != nil {
+				f.Fatal(err)
+			}
+			f.Add(der)
+			f.Add(der[:len(der)-1])
+		}
+		key.private.(interface{ Clean() }).Clean()
+	}
+
+	f.Fuzz(func(t *testing.T, der []byte) {
+		key, err := oqs.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			return
+		}
+		defer key.(interface{ Clean() }).Clean()
+		reencoded, err := oqs.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("parsed private key does not re-encode: %v", err)
+		}
+		reparsed, err := oqs.ParsePKCS8PrivateKey(reencoded)
+		if err != nil {
+			t.Fatalf("re-encoded private key does not parse: %v", err)
+		}
+		defer reparsed.(interface{ Clean() }).Clean()
+		if again, _ := oqs.MarshalPKCS8PrivateKey(reparsed); !bytes.Equal(again, reencoded) {
+			t.Fatalf("re-encoded private keys do not coincide")
+		}
+	})
+}
+
+// FuzzParseCompositeKey parses mutated composite public and private keys of
+// all supported composite signatures whose ML-DSA component is enabled.
+func FuzzParseCompositeKey(f *testing.F) {
+	var algNames []string
+	for _, algName := range oqs.SupportedCompositeSigs() {
+		priv, err := oqs.GenerateCompositeKey(algName)
+		if errors.Is(err, oqs.ErrAlgorithmNotEnabled) {
+			continue
+		} else if err != nil {
+			f.Fatalf("%s: %v", algName, err)
+		}
+		pubDER, _ := priv.Public().(*oqs.CompositePublicKey).Bytes()
+		privDER, err := priv.Bytes()
+		if err != nil {
+			f.Fatalf("%s: %v", algName, err)
+		}
+		priv.Clean()
+		f.Add(uint8(len(algNames)), pubDER, privDER)
+		f.Add(uint8(len(algNames)), pubDER[:len(pubDER)-1], privDER[:len(privDER)-1])
+		algNames = append(algNames, algName)
+	}
+	if len(algNames) == 0 {
+		f.Skip("no composite signature is enabled")
+	}
+
+	f.Fuzz(func(t *testing.T, alg uint8, pubDER, privDER []byte) {
+		algName := algNames[int(alg)%len(algNames)]
+		if pub, err := oqs.ParseCompositePublicKey(algName, pubDER); err == nil {
+			reencoded, err := pub.Bytes()
+			if err != nil {
+				t.Fatalf("%s: parsed public key does not re-encode: %v", algName, err)
+			}
+			if reparsed, err := oqs.ParseCompositePublicKey(algName, reencoded); err != nil ||
+				!reparsed.Equal(pub) {
+				t.Fatalf("%s: re-encoded public key does not parse to the same key", algName)
+			}
+		}
+		if priv, err := oqs.ParseCompositePrivateKey(algName, privDER); err == nil {
+			defer priv.Clean()
+			if _, err := priv.Bytes(); err != nil {
+				t.Fatalf("%s: parsed private key does not re-encode: %v", algName, err)
+			}
+		}
+	})
+}
+
+// pkixKey is a key pair with a NIST-assigned OID, in the seed corpus of the
+// key parser fuzz targets.
+type pkixKey struct {
+	public  any
+	private any
+}
+
+// fuzzPKIXKeys generates a key pair for each enabled algorithm with an OID,
+// from a seed whenever deterministic key generation is supported, so that
+// all the private key formats are covered.
+func fuzzPKIXKeys(f *testing.F) []pkixKey {
+	var keys []pkixKey
+	for _, kemName := range oqs.EnabledKEMs() {
+		if _, err := oqs.AlgorithmOID(kemName); err != nil {
+			continue
+		}
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		var publicKey []byte
+		var err error
+		if n := kem.Details().LengthKeypairSeed; n > 0 {
+			publicKey, err = kem.GenerateKeyPairFromSeed(oqs.RandomBytes(n))
+		} else {
+			publicKey, err = kem.GenerateKeyPair()
+		}
+		if err != nil {
+			f.Fatalf("%s: %v", kemName, err)
+		}
+		pub, _ := oqs.NewKEMPublicKey(kemName, publicKey)
+		keys = append(keys, pkixKey{pub, kem})
+	}
+	for _, sigName := range oqs.EnabledSigs() {
+		if _, err := oqs.AlgorithmOID(sigName); err != nil {
+			continue
+		}
+		sig, _ := oqs.NewSignature(sigName)
+		var publicKey []byte
+		var err error
+		if n := sig.Details().LengthKeypairSeed; n > 0 {
+			publicKey, err = sig.GenerateKeyPairFromSeed(oqs.RandomBytes(n))
+		} else {
+			publicKey, err = sig.GenerateKeyPair()
+		}
+		if err != nil {
+			f.Fatalf("%s: %v", sigName, err)
+		}
+		pub, _ := oqs.NewSigPublicKey(sigName, publicKey)
+		keys = append(keys, pkixKey{pub, sig})
+	}
+	return keys
+}
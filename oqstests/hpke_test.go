@@ -0,0 +1,239 @@
+package oqstests
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs/hpke"
+)
+
+var (
+	hpkeKEMs  = []hpke.KEM{hpke.KEMMLKEM512, hpke.KEMMLKEM768, hpke.KEMMLKEM1024, hpke.KEMXWing}
+	hpkeKDFs  = []hpke.KDF{hpke.KDFHKDFSHA256, hpke.KDFHKDFSHA384, hpke.KDFHKDFSHA512}
+	hpkeAEADs = []hpke.AEAD{hpke.AEADAES128GCM, hpke.AEADAES256GCM, hpke.AEADChaCha20Poly1305}
+)
+
+// testHPKECorrectness seals and opens a sequence of messages in the given
+// mode, and checks that the exported secrets coincide.
+func testHPKECorrectness(suite hpke.Suite, psk, pskID []byte, t *testing.T) {
+	info := []byte("HPKE test info")
+	publicKey, secretKey, err := suite.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("%s: %v", suite, err)
+	}
+	var sender *hpke.Sender
+	if psk == nil {
+		sender, err = suite.NewSender(publicKey, info)
+	} else {
+		sender, err = suite.NewSenderPSK(publicKey, info, psk, pskID)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", suite, err)
+	}
+	var receiver *hpke.Receiver
+	if psk == nil {
+		receiver, err = suite.NewReceiver(secretKey, sender.Enc(), info)
+	} else {
+		receiver, err = suite.NewReceiverPSK(secretKey, sender.Enc(), info, psk, pskID)
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", suite, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		aad := []byte{byte(i)}
+		plaintext := bytes.Repeat([]byte("message"), i)
+		ciphertext, err := sender.Seal(aad, plaintext)
+		if err != nil {
+			t.Fatalf("%s: %v", suite, err)
+		}
+		if i == 1 {
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[0] ^= 1
+			if _, err := receiver.Open(aad, tampered); !errors.Is(err, hpke.ErrOpen) {
+				t.Errorf("%s: expected ErrOpen, got %v", suite, err)
+			}
+		}
+		opened, err := receiver.Open(aad, ciphertext)
+		if err != nil {
+			t.Errorf("%s: message %d: %v", suite, i, err)
+		} else if !bytes.Equal(opened, plaintext) {
+			t.Errorf("%s: message %d does not coincide", suite, i)
+		}
+	}
+
+	exportSender, _ := sender.Export([]byte("context"), 42)
+	exportReceiver, _ := receiver.Export([]byte("context"), 42)
+	if len(exportSender) != 42 || !bytes.Equal(exportSender, exportReceiver) {
+		t.Errorf("%s: exported secrets do not coincide", suite)
+	}
+	if _, err := sender.Export(nil, 1<<20); !errors.Is(err, hpke.ErrExportLength) {
+		t.Errorf("%s: expected ErrExportLength, got %v", suite, err)
+	}
+}
+
+// TestHPKE tests all the supported HPKE cipher suites in Base and PSK modes.
+func TestHPKE(t *testing.T) {
+	for _, kemID := range hpkeKEMs {
+		for _, kdfID := range hpkeKDFs {
+			for _, aeadID := range hpkeAEADs {
+				suite, err := hpke.NewSuite(kemID, kdfID, aeadID)
+				if errors.Is(err, hpke.ErrUnsupportedSuite) {
+					continue
+				}
+				log.Println("HPKE - ", suite)
+				testHPKECorrectness(suite, nil, nil, t)
+				testHPKECorrectness(suite, []byte("pre-shared key"), []byte("psk id"), t)
+			}
+		}
+	}
+}
+
+// TestHPKEErrors tests the single-shot API, the PSK input validation and the
+// export-only AEAD.
+func TestHPKEErrors(t *testing.T) {
+	if _, err := hpke.NewSuite(0x0020, hpke.KDFHKDFSHA256, hpke.AEADAES128GCM); !errors.Is(err, hpke.ErrUnsupportedSuite) {
+		t.Errorf("expected ErrUnsupportedSuite, got %v", err)
+	}
+	suite, err := hpke.NewSuite(hpke.KEMMLKEM768, hpke.KDFHKDFSHA256, hpke.AEADAES128GCM)
+	if err != nil {
+		t.Skip("ML-KEM-768 is not enabled")
+	}
+	publicKey, secretKey, _ := suite.GenerateKeyPair()
+	info, aad, msg := []byte("info"), []byte("aad"), []byte("sealed box")
+
+	enc, ciphertext, err := suite.Seal(publicKey, info, aad, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := suite.Open(secretKey, enc, info, aad, ciphertext); err != nil || !bytes.Equal(opened, msg) {
+		t.Errorf("single-shot open failed: %v", err)
+	}
+	if _, err := suite.Open(secretKey, enc, []byte("other info"), aad, ciphertext); !errors.Is(err, hpke.ErrOpen) {
+		t.Errorf("expected ErrOpen for a different info, got %v", err)
+	}
+
+	if _, err := suite.NewSenderPSK(publicKey, info, []byte("psk"), nil); !errors.Is(err, hpke.ErrInconsistentPSK) {
+		t.Errorf("expected ErrInconsistentPSK, got %v", err)
+	}
+	if _, err := suite.NewSenderPSK(publicKey, info, nil, nil); !errors.Is(err, hpke.ErrInconsistentPSK) {
+		t.Errorf("expected ErrInconsistentPSK, got %v", err)
+	}
+	sender, _ := suite.NewSenderPSK(publicKey, info, []byte("psk"), []byte("id"))
+	ciphertext, _ = sender.Seal(aad, msg)
+	receiver, _ := suite.NewReceiverPSK(secretKey, sender.Enc(), info, []byte("other psk"), []byte("id"))
+	if _, err := receiver.Open(aad, ciphertext); !errors.Is(err, hpke.ErrOpen) {
+		t.Errorf("expected ErrOpen for a wrong PSK, got %v", err)
+	}
+
+	exportOnly, _ := hpke.NewSuite(hpke.KEMMLKEM768, hpke.KDFHKDFSHA256, hpke.AEADExportOnly)
+	sender, _ = exportOnly.NewSender(publicKey, info)
+	if _, err := sender.Seal(aad, msg); !errors.Is(err, hpke.ErrExportOnly) {
+		t.Errorf("expected ErrExportOnly, got %v", err)
+	}
+	receiver, _ = exportOnly.NewReceiver(secretKey, sender.Enc(), info)
+	exportSender, _ := sender.Export(nil, 32)
+	exportReceiver, _ := receiver.Export(nil, 32)
+	if !bytes.Equal(exportSender, exportReceiver) {
+		t.Errorf("exported secrets do not coincide")
+	}
+}
+
+// hexBytes is a hex-encoded JSON byte string.
+type hexBytes []byte
+
+// UnmarshalJSON decodes a hex-encoded JSON string.
+func (b *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	*b = decoded
+	return err
+}
+
+// hpkeVector is a Base mode test vector of the HPKE post-quantum drafts.
+type hpkeVector struct {
+	Mode        int       `json:"mode"`
+	KEM         hpke.KEM  `json:"kem_id"`
+	KDF         hpke.KDF  `json:"kdf_id"`
+	AEAD        hpke.AEAD `json:"aead_id"`
+	Info        hexBytes  `json:"info"`
+	IkmE        hexBytes  `json:"ikmE"`
+	IkmR        hexBytes  `json:"ikmR"`
+	SkRm        hexBytes  `json:"skRm"`
+	PkRm        hexBytes  `json:"pkRm"`
+	Enc         hexBytes  `json:"enc"`
+	Encryptions []struct {
+		Aad hexBytes `json:"aad"`
+		Ct  hexBytes `json:"ct"`
+		Pt  hexBytes `json:"pt"`
+	} `json:"encryptions"`
+	Exports []struct {
+		ExporterContext hexBytes `json:"exporter_context"`
+		L               int      `json:"L"`
+		ExportedValue   hexBytes `json:"exported_value"`
+	} `json:"exports"`
+}
+
+// TestHPKEVectors tests the supported suites against the test vectors of
+// draft-ietf-hpke-pq, in testdata/hpke/hpke-pq.json.
+func TestHPKEVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/hpke/hpke-pq.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors []hpkeVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	tested := 0
+	for _, v := range vectors {
+		suite, err := hpke.NewSuite(v.KEM, v.KDF, v.AEAD)
+		if v.Mode != 0 || errors.Is(err, hpke.ErrUnsupportedSuite) {
+			continue
+		}
+		log.Println("HPKE test vector - ", suite)
+		tested++
+		publicKey, secretKey, err := suite.DeriveKeyPair(v.IkmR)
+		if err != nil {
+			t.Fatalf("%s: %v", suite, err)
+		}
+		if !bytes.Equal(publicKey, v.PkRm) || !bytes.Equal(secretKey, v.SkRm) {
+			t.Errorf("%s: derived key pair does not coincide", suite)
+		}
+		sender, err := suite.NewSenderDeterministic(v.PkRm, v.Info, v.IkmE)
+		if err != nil {
+			t.Fatalf("%s: %v", suite, err)
+		}
+		if !bytes.Equal(sender.Enc(), v.Enc) {
+			t.Errorf("%s: encapsulated keys do not coincide", suite)
+		}
+		receiver, err := suite.NewReceiver(v.SkRm, v.Enc, v.Info)
+		if err != nil {
+			t.Fatalf("%s: %v", suite, err)
+		}
+		for i, e := range v.Encryptions {
+			if ciphertext, _ := sender.Seal(e.Aad, e.Pt); !bytes.Equal(ciphertext, e.Ct) {
+				t.Errorf("%s: ciphertext %d does not coincide", suite, i)
+			}
+			if plaintext, err := receiver.Open(e.Aad, e.Ct); err != nil || !bytes.Equal(plaintext, e.Pt) {
+				t.Errorf("%s: message %d: %v", suite, i, err)
+			}
+		}
+		for i, e := range v.Exports {
+			if exported, _ := receiver.Export(e.ExporterContext, e.L); !bytes.Equal(exported, e.ExportedValue) {
+				t.Errorf("%s: exported value %d does not coincide", suite, i)
+			}
+		}
+	}
+	if tested == 0 {
+		t.Skip("no HPKE test vector suite is enabled")
+	}
+}
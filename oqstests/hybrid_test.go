@@ -0,0 +1,285 @@
+package oqstests
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdh"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+	"golang.org/x/crypto/sha3"
+)
+
+// testHybridKEMCorrectness tests the correctness of a specific hybrid KEM.
+func testHybridKEMCorrectness(client, server *oqs.HybridKeyEncapsulation, t *testing.T) {
+	name := client.Details().Name
+	clientPublicKey, err := client.GenerateKeyPair()
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if len(clientPublicKey) != client.Details().LengthPublicKey {
+		t.Errorf("%s: incorrect public key length", name)
+	}
+	ciphertext, sharedSecretServer, err := server.EncapSecret(clientPublicKey)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if len(ciphertext) != client.Details().LengthCiphertext ||
+		len(sharedSecretServer) != client.Details().LengthSharedSecret {
+		t.Errorf("%s: incorrect ciphertext or shared secret length", name)
+	}
+	sharedSecretClient, err := client.DecapSecret(ciphertext)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if !bytes.Equal(sharedSecretClient, sharedSecretServer) {
+		t.Errorf("%s: shared secrets do not coincide", name)
+	}
+}
+
+// TestHybridKeyEncapsulation tests the correctness of all named hybrid KEMs.
+func TestHybridKeyEncapsulation(t *testing.T) {
+	for _, name := range oqs.SupportedHybridKEMs() {
+		client, err := oqs.NewHybridKeyEncapsulation(name)
+		if errors.Is(err, oqs.ErrAlgorithmNotEnabled) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		log.Println("Hybrid correctness - ", name)
+		server, _ := oqs.NewHybridKeyEncapsulation(name)
+		testHybridKEMCorrectness(client, server, t)
+
+		// Re-import the client secret key and decapsulate again
+		clientPublicKey, _ := client.GenerateKeyPair()
+		ciphertext, sharedSecretServer, _ := server.EncapSecret(clientPublicKey)
+		imported, err := oqs.NewHybridKeyEncapsulation(name,
+			oqs.WithSecretKey(client.ExportSecretKey()))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		sharedSecretImported, _ := imported.DecapSecret(ciphertext)
+		if !bytes.Equal(sharedSecretImported, sharedSecretServer) {
+			t.Errorf("%s: shared secrets do not coincide after import", name)
+		}
+
+		// Corrupt the post-quantum part of the ciphertext
+		wrongCiphertext := append([]byte(nil), ciphertext...)
+		wrongCiphertext[len(wrongCiphertext)/2] ^= 0x01
+		sharedSecretWrong, _ := client.DecapSecret(wrongCiphertext)
+		if bytes.Equal(sharedSecretWrong, sharedSecretServer) {
+			t.Errorf("%s: shared secrets should not coincide", name)
+		}
+
+		if _, _, err := server.EncapSecret(clientPublicKey[1:]); !errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: expected ErrInvalidPublicKeyLength, got %v", name, err)
+		}
+		imported.Clean()
+		server.Clean()
+		client.Clean()
+	}
+	if _, err := oqs.NewHybridKeyEncapsulation("unsupported_hybrid"); !errors.Is(err, oqs.ErrAlgorithmNotSupported) {
+		t.Errorf("Expected ErrAlgorithmNotSupported, got %v", err)
+	}
+}
+
+// TestCustomHybridKeyEncapsulation tests hybrid KEMs made of arbitrary curves
+// and enabled KEMs.
+func TestCustomHybridKeyEncapsulation(t *testing.T) {
+	for _, curve := range []ecdh.Curve{ecdh.X25519(), ecdh.P256(), ecdh.P384()} {
+		for _, kemName := range oqs.EnabledKEMs() {
+			client, err := oqs.NewCustomHybridKeyEncapsulation(curve, kemName, oqs.CombinerConcat)
+			if err != nil {
+				t.Fatalf("%v+%s: %v", curve, kemName, err)
+			}
+			server, _ := oqs.NewCustomHybridKeyEncapsulation(curve, kemName, oqs.CombinerConcat)
+			testHybridKEMCorrectness(client, server, t)
+			server.Clean()
+			client.Clean()
+		}
+	}
+	if _, err := oqs.NewCustomHybridKeyEncapsulation(ecdh.P256(), "ML-KEM-768", oqs.CombinerXWing); !errors.Is(err, oqs.ErrAlgorithmNotSupported) {
+		t.Errorf("X-Wing combiner with P-256 should have emitted an error")
+	}
+}
+
+// xwingVectorsChecksum is the SHAKE128 digest of the test vectors of
+// draft-connolly-cfrg-xwing-kem-05, as published with the draft.
+const xwingVectorsChecksum = "1bcd0057d861d6b866239936cadcaeee1ec0164dedc181c386e9e54fe46156fe"
+
+// readXWingVectors parses testdata/xwing/test-vectors.txt, i.e. blank-line
+// separated groups of hex-encoded seed, sk, pk, eseed, ct and ss values.
+func readXWingVectors(t *testing.T) []map[string][]byte {
+	data, err := os.ReadFile("testdata/xwing/test-vectors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := make([]byte, 32)
+	sha3.ShakeSum128(checksum, data)
+	if hex.EncodeToString(checksum) != xwingVectorsChecksum {
+		t.Fatalf("X-Wing: test vectors checksum mismatch")
+	}
+	var vectors []map[string][]byte
+	vector := map[string][]byte{}
+	var key, value string
+	flush := func() {
+		if key != "" {
+			b, err := hex.DecodeString(value)
+			if err != nil {
+				t.Fatalf("X-Wing: %s: %v", key, err)
+			}
+			vector[key] = b
+		}
+		key, value = "", ""
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+			vectors = append(vectors, vector)
+			vector = map[string][]byte{}
+		case strings.HasPrefix(line, "  "):
+			value += strings.TrimSpace(line)
+		default:
+			flush()
+			fields := strings.Fields(line)
+			key = fields[0]
+			if len(fields) > 1 {
+				value = fields[1]
+			}
+		}
+	}
+	return vectors
+}
+
+// TestXWingVectors tests X-Wing against the test vectors of
+// draft-connolly-cfrg-xwing-kem-05.
+func TestXWingVectors(t *testing.T) {
+	if !oqs.IsKEMEnabled("ML-KEM-768") {
+		t.Skip("ML-KEM-768 is not enabled")
+	}
+	vectors := readXWingVectors(t)
+	if len(vectors) != 3 {
+		t.Fatalf("X-Wing: expected 3 test vectors, got %d", len(vectors))
+	}
+	for i, v := range vectors {
+		log.Println("X-Wing test vector - ", i)
+		client, _ := oqs.NewHybridKeyEncapsulation("X-Wing")
+		publicKey, err := client.GenerateKeyPairFromSeed(v["seed"])
+		if err != nil {
+			t.Fatalf("X-Wing %d: %v", i, err)
+		}
+		if !bytes.Equal(publicKey, v["pk"]) {
+			t.Errorf("X-Wing %d: public keys do not coincide", i)
+		}
+		if !bytes.Equal(client.ExportSecretKey(), v["sk"]) {
+			t.Errorf("X-Wing %d: secret keys do not coincide", i)
+		}
+		ciphertext, sharedSecret, err := client.EncapSecretDeterministic(v["pk"], v["eseed"])
+		if err != nil {
+			t.Fatalf("X-Wing %d: %v", i, err)
+		}
+		if !bytes.Equal(ciphertext, v["ct"]) || !bytes.Equal(sharedSecret, v["ss"]) {
+			t.Errorf("X-Wing %d: ciphertexts or shared secrets do not coincide", i)
+		}
+		client.Clean()
+
+		// Decapsulate with the secret key, i.e. the seed
+		imported, err := oqs.NewHybridKeyEncapsulation("X-Wing", oqs.WithSecretKey(v["sk"]))
+		if err != nil {
+			t.Fatalf("X-Wing %d: %v", i, err)
+		}
+		if decapsulated, _ := imported.DecapSecret(v["ct"]); !bytes.Equal(decapsulated, v["ss"]) {
+			t.Errorf("X-Wing %d: decapsulated shared secrets do not coincide", i)
+		}
+
+		// A ciphertext whose X25519 part is a low-order point, here 0, yields
+		// the all-zero X25519 shared secret, which X-Wing hashes as any other.
+		ctM := v["ct"][:len(v["ct"])-32]
+		lowOrder := append(append([]byte(nil), ctM...), make([]byte, 32)...)
+		decapsulated, err := imported.DecapSecret(lowOrder)
+		if err != nil {
+			t.Fatalf("X-Wing %d: low-order point: %v", i, err)
+		}
+		if again, _ := imported.DecapSecret(lowOrder); !bytes.Equal(again, decapsulated) {
+			t.Errorf("X-Wing %d: low-order point: decapsulation is not deterministic", i)
+		}
+		expanded := make([]byte, 96)
+		sha3.ShakeSum256(expanded, v["sk"])
+		mlkem, _ := oqs.NewKeyEncapsulation("ML-KEM-768")
+		_, _ = mlkem.GenerateKeyPairFromSeed(expanded[:64])
+		ssM, _ := mlkem.DecapSecret(ctM)
+		mlkem.Clean()
+		hash := sha3.New256()
+		hash.Write(ssM)
+		hash.Write(make([]byte, 64)) // ss_X and ct_X
+		hash.Write(v["pk"][len(v["pk"])-32:])
+		hash.Write([]byte(`\.//^\`))
+		if !bytes.Equal(decapsulated, hash.Sum(nil)) {
+			t.Errorf("X-Wing %d: low-order point: shared secrets do not coincide", i)
+		}
+		imported.Clean()
+	}
+
+	// Unlike X-Wing, the TLS hybrids reject the low-order X25519 points.
+	client, _ := oqs.NewHybridKeyEncapsulation("X25519MLKEM768")
+	defer client.Clean()
+	publicKey, _ := client.GenerateKeyPair()
+	ciphertext, _, _ := client.EncapSecret(publicKey)
+	clear(ciphertext[len(ciphertext)-32:])
+	if _, err := client.DecapSecret(ciphertext); !errors.Is(err, oqs.ErrInvalidCiphertext) {
+		t.Errorf("X25519MLKEM768: expected ErrInvalidCiphertext, got %v", err)
+	}
+}
+
+// TestHybridDeterministic tests that the hybrid KEMs derive the same key pairs
+// and ciphertexts from the same seeds.
+func TestHybridDeterministic(t *testing.T) {
+	for _, name := range oqs.SupportedHybridKEMs() {
+		client, err := oqs.NewHybridKeyEncapsulation(name)
+		if errors.Is(err, oqs.ErrAlgorithmNotEnabled) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		log.Println("Hybrid deterministic - ", name)
+		details := client.Details()
+		seed := bytes.Repeat([]byte{0x01}, details.LengthKeypairSeed)
+		publicKey, err := client.GenerateKeyPairFromSeed(seed)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		again, _ := oqs.NewHybridKeyEncapsulation(name)
+		if publicKeyAgain, _ := again.GenerateKeyPairFromSeed(seed); !bytes.Equal(publicKeyAgain, publicKey) {
+			t.Errorf("%s: public keys do not coincide", name)
+		}
+		if _, err := again.GenerateKeyPairFromSeed(seed[1:]); !errors.Is(err, oqs.ErrInvalidSeedLength) {
+			t.Errorf("%s: expected ErrInvalidSeedLength, got %v", name, err)
+		}
+		if len(client.ExportSecretKey()) != details.LengthSecretKey {
+			t.Errorf("%s: incorrect secret key length", name)
+		}
+		encapsSeed := bytes.Repeat([]byte{0x02}, details.LengthEncapsSeed)
+		ciphertext, sharedSecret, err := again.EncapSecretDeterministic(publicKey, encapsSeed)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if ciphertextAgain, _, _ := again.EncapSecretDeterministic(publicKey, encapsSeed); !bytes.Equal(ciphertextAgain, ciphertext) {
+			t.Errorf("%s: ciphertexts do not coincide", name)
+		}
+		if decapsulated, _ := client.DecapSecret(ciphertext); !bytes.Equal(decapsulated, sharedSecret) {
+			t.Errorf("%s: shared secrets do not coincide", name)
+		}
+		again.Clean()
+		client.Clean()
+	}
+}
@@ -0,0 +1,373 @@
+package oqstests
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// katDir holds the KAT vectors, one gzip-compressed .rsp file per algorithm,
+// and the SHA-256 digests of the .rsp files, in kats.json, in the kem and sig
+// subdirectories. They are generated by the katgen program with an ML-KEM and
+// ML-DSA implementation independent of liboqs. The LIBOQS_KATS_DIR
+// environment variable may point to the tests/KATs directory of the liboqs
+// sources to also check the digests of liboqs, which cover all algorithms.
+// An enabled algorithm with neither a vector nor a digest fails the test.
+const katDir = "testdata/kat"
+
+/**************** NIST AES-CTR DRBG ****************/
+
+// nistDRBG is the AES-256 CTR DRBG, without derivation function nor
+// reseeding, of the NIST PQC KAT generators (rng.c).
+type nistDRBG struct {
+	key [32]byte
+	v   [aes.BlockSize]byte
+}
+
+// newNISTDRBG instantiates the DRBG with the 48-byte entropy input, as
+// randombytes_init(entropy, NULL, 256) does.
+func newNISTDRBG(entropy []byte) *nistDRBG {
+	d := &nistDRBG{}
+	d.update(entropy)
+	return d
+}
+
+// increment increments the counter V as a big-endian integer.
+func (d *nistDRBG) increment() {
+	for j := len(d.v) - 1; j >= 0; j-- {
+		d.v[j]++
+		if d.v[j] != 0 {
+			break
+		}
+	}
+}
+
+// update is AES256_CTR_DRBG_Update.
+func (d *nistDRBG) update(providedData []byte) {
+	block, _ := aes.NewCipher(d.key[:])
+	var temp [48]byte
+	for i := 0; i < 3; i++ {
+		d.increment()
+		block.Encrypt(temp[16*i:], d.v[:])
+	}
+	for i := range providedData {
+		temp[i] ^= providedData[i]
+	}
+	copy(d.key[:], temp[:32])
+	copy(d.v[:], temp[32:])
+}
+
+// Read fills p as a single randombytes(p, len(p)) call does. Note that the
+// output depends on how the requests are split, as for the C implementation.
+func (d *nistDRBG) Read(p []byte) (int, error) {
+	block, _ := aes.NewCipher(d.key[:])
+	var out [aes.BlockSize]byte
+	for i := 0; i < len(p); i += aes.BlockSize {
+		d.increment()
+		block.Encrypt(out[:], d.v[:])
+		copy(p[i:], out[:])
+	}
+	d.update(nil)
+	return len(p), nil
+}
+
+/**************** END NIST AES-CTR DRBG ****************/
+
+// katEntropyInput returns the entropy input 0, 1, ..., 47 of the NIST PQC
+// KAT generators.
+func katEntropyInput() []byte {
+	entropy := make([]byte, 48)
+	for i := range entropy {
+		entropy[i] = byte(i)
+	}
+	return entropy
+}
+
+// withKATRand runs f with OQS_randombytes drawing from a NIST DRBG seeded
+// with seed, then switches back to the system RNG.
+func withKATRand(seed []byte, f func() error) error {
+	drbg := newNISTDRBG(seed)
+	if err := oqs.RandomBytesCustomAlgorithm(func(randomArray []byte, bytesToRead int) {
+		_, _ = drbg.Read(randomArray[:bytesToRead])
+	}); err != nil {
+		return err
+	}
+	defer func() { _ = oqs.RandomBytesSwitchAlgorithm("system") }()
+	return f()
+}
+
+// fprintBstr writes a hex-encoded field as the fprintBstr function of the
+// NIST PQC KAT generators does.
+func fprintBstr(w io.Writer, label string, data []byte) {
+	if len(data) == 0 {
+		fmt.Fprintf(w, "%s00\n", label)
+		return
+	}
+	fmt.Fprintf(w, "%s%X\n", label, data)
+}
+
+// kemTranscript returns the .rsp transcript of the KEM kemName, in the format
+// of the kat_kem program of liboqs.
+func kemTranscript(kemName string) ([]byte, error) {
+	var rsp bytes.Buffer
+	seed := make([]byte, 48)
+	_, _ = newNISTDRBG(katEntropyInput()).Read(seed)
+	fmt.Fprintf(&rsp, "count = 0\n")
+	fprintBstr(&rsp, "seed = ", seed)
+
+	kem, err := oqs.NewKeyEncapsulation(kemName)
+	if err != nil {
+		return nil, err
+	}
+	defer kem.Clean()
+	err = withKATRand(seed, func() error {
+		publicKey, err := kem.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+		fprintBstr(&rsp, "pk = ", publicKey)
+		fprintBstr(&rsp, "sk = ", kem.ExportSecretKey())
+		ciphertext, sharedSecret, err := kem.EncapSecret(publicKey)
+		if err != nil {
+			return err
+		}
+		fprintBstr(&rsp, "ct = ", ciphertext)
+		fprintBstr(&rsp, "ss = ", sharedSecret)
+		decapsulated, err := kem.DecapSecret(ciphertext)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(sharedSecret, decapsulated) {
+			return errors.New("shared secrets do not coincide")
+		}
+		return nil
+	})
+	return rsp.Bytes(), err
+}
+
+// combineMessageSignature returns the signed message sm of the signature
+// sigName, as the combine_message_signature function of the kat_sig program
+// of liboqs builds it from the reference implementations' formats.
+func combineMessageSignature(sigName string, msg, signature []byte) []byte {
+	var sm []byte
+	switch {
+	case strings.HasPrefix(sigName, "Falcon-") && len(signature) > 41:
+		// BE16(len) || nonce || msg || 0x20 + logn || compressed signature,
+		// where signature = header || 40-byte nonce || compressed signature
+		// and len counts the header byte and the compressed signature
+		header := byte(0x29)
+		if strings.HasSuffix(sigName, "1024") {
+			header = 0x2a
+		}
+		sigLen := len(signature) - 40
+		sm = append(sm, byte(sigLen>>8), byte(sigLen))
+		sm = append(sm, signature[1:41]...)
+		sm = append(sm, msg...)
+		sm = append(sm, header)
+		sm = append(sm, signature[41:]...)
+	case strings.HasPrefix(sigName, "cross-"), strings.HasPrefix(sigName, "OV-"):
+		sm = append(append(sm, msg...), signature...)
+	default:
+		sm = append(append(sm, signature...), msg...)
+	}
+	return sm
+}
+
+// sigTranscript returns the .rsp transcript of the signature sigName, in the
+// format of the kat_sig program of liboqs.
+func sigTranscript(sigName string) ([]byte, error) {
+	var rsp bytes.Buffer
+	drbg := newNISTDRBG(katEntropyInput())
+	seed := make([]byte, 48)
+	_, _ = drbg.Read(seed)
+	fmt.Fprintf(&rsp, "count = 0\n")
+	fprintBstr(&rsp, "seed = ", seed)
+	msg := make([]byte, 33)
+	_, _ = drbg.Read(msg)
+	fmt.Fprintf(&rsp, "mlen = %d\n", len(msg))
+	fprintBstr(&rsp, "msg = ", msg)
+
+	sig, err := oqs.NewSignature(sigName)
+	if err != nil {
+		return nil, err
+	}
+	defer sig.Clean()
+	err = withKATRand(seed, func() error {
+		publicKey, err := sig.GenerateKeyPair()
+		if err != nil {
+			return err
+		}
+		fprintBstr(&rsp, "pk = ", publicKey)
+		fprintBstr(&rsp, "sk = ", sig.ExportSecretKey())
+		signature, err := sig.Sign(msg)
+		if err != nil {
+			return err
+		}
+		sm := combineMessageSignature(sigName, msg, signature)
+		fmt.Fprintf(&rsp, "smlen = %d\n", len(sm))
+		fprintBstr(&rsp, "sm = ", sm)
+		if isValid, err := sig.Verify(msg, signature, publicKey); err != nil {
+			return err
+		} else if !isValid {
+			return errors.New("signature verification failed")
+		}
+		return nil
+	})
+	return rsp.Bytes(), err
+}
+
+// readKATDigests reads the kats.json digests of kind, i.e. "kem" or "sig",
+// under dir. The digests of liboqs are either strings or objects whose
+// "single" member is the digest of the count = 0 transcript.
+func readKATDigests(dir, kind string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, kind, "kats.json"))
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	digests := make(map[string]string)
+	for algName, entry := range entries {
+		var digest string
+		if err := json.Unmarshal(entry, &digest); err == nil {
+			digests[algName] = digest
+			continue
+		}
+		var variants struct {
+			Single string `json:"single"`
+		}
+		if err := json.Unmarshal(entry, &variants); err != nil {
+			return nil, fmt.Errorf("%s: %w", algName, err)
+		}
+		if variants.Single != "" {
+			digests[algName] = variants.Single
+		}
+	}
+	return digests, nil
+}
+
+// readKATVector reads the gzip-compressed .rsp file of algName, or returns
+// nil if there is none.
+func readKATVector(kind, algName string) ([]byte, error) {
+	f, err := os.Open(filepath.Join(katDir, kind, algName+".rsp.gz"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// firstDifference returns the first line of got that differs from want.
+func firstDifference(got, want []byte) string {
+	gotLines := bufio.NewScanner(bytes.NewReader(got))
+	gotLines.Buffer(nil, len(got)+1)
+	wantLines := bufio.NewScanner(bytes.NewReader(want))
+	wantLines.Buffer(nil, len(want)+1)
+	for gotLines.Scan() {
+		if !wantLines.Scan() || gotLines.Text() != wantLines.Text() {
+			field, _, _ := strings.Cut(gotLines.Text(), " = ")
+			return field
+		}
+	}
+	return "end of transcript"
+}
+
+// checkKATs computes the transcripts of all the algorithms algNames and checks
+// them against the stored vectors and digests of kind, and against the
+// digests of liboqs if LIBOQS_KATS_DIR is set.
+func checkKATs(t *testing.T, kind string, algNames []string,
+	transcript func(string) ([]byte, error),
+) {
+	digests, err := readKATDigests(katDir, kind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var liboqsDigests map[string]string
+	if dir := os.Getenv("LIBOQS_KATS_DIR"); dir != "" {
+		if liboqsDigests, err = readKATDigests(dir, kind); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, algName := range algNames {
+		log.Println("KAT - ", algName)
+		want, err := readKATVector(kind, algName)
+		if err != nil {
+			t.Fatalf("%s: %v", algName, err)
+		}
+		got, err := transcript(algName)
+		if err != nil {
+			t.Errorf("%s: %v", algName, err)
+			continue
+		}
+		sum := sha256.Sum256(got)
+		digest := hex.EncodeToString(sum[:])
+		checked := false
+		if want != nil {
+			checked = true
+			if !bytes.Equal(got, want) {
+				t.Errorf("%s: KAT mismatch at %s", algName,
+					firstDifference(got, want))
+			}
+		}
+		if wantDigest, ok := digests[algName]; ok {
+			checked = true
+			if !strings.EqualFold(digest, wantDigest) {
+				t.Errorf("%s: KAT digest mismatch", algName)
+			}
+		}
+		if wantDigest, ok := liboqsDigests[algName]; ok {
+			checked = true
+			if !strings.EqualFold(digest, wantDigest) {
+				t.Errorf("%s: KAT digest mismatch with liboqs", algName)
+			}
+		}
+		if !checked {
+			t.Errorf("%s: no KAT vector nor digest, transcript digest %s",
+				algName, digest)
+		}
+	}
+}
+
+// TestNISTDRBG tests the DRBG against the first seed of the NIST PQC KAT
+// files, which is derived from the entropy input 0, 1, ..., 47.
+func TestNISTDRBG(t *testing.T) {
+	seed := make([]byte, 48)
+	_, _ = newNISTDRBG(katEntropyInput()).Read(seed)
+	want := "061550234D158C5EC95595FE04EF7A25767F2E24CC2BC479D09D86DC9ABCFDE7056A8C266F9EF97ED08541DBD2E1FFA1"
+	if got := fmt.Sprintf("%X", seed); got != want {
+		t.Errorf("DRBG output %s, expected %s", got, want)
+	}
+}
+
+// TestKEMKAT checks the known-answer tests of all enabled KEMs.
+func TestKEMKAT(t *testing.T) {
+	checkKATs(t, "kem", oqs.EnabledKEMs(), kemTranscript)
+}
+
+// TestSigKAT checks the known-answer tests of all enabled signatures.
+func TestSigKAT(t *testing.T) {
+	checkKATs(t, "sig", oqs.EnabledSigs(), sigTranscript)
+}
@@ -0,0 +1,7 @@
+module github.com/open-quantum-safe/liboqs-go/oqstests/katgen
+
+go 1.22.0
+
+require github.com/cloudflare/circl v1.6.1
+
+require golang.org/x/sys v0.10.0 // indirect
@@ -0,0 +1,268 @@
+// Command katgen generates the known-answer test vectors of oqstests for
+// ML-KEM and ML-DSA with an implementation independent of liboqs, namely
+// github.com/cloudflare/circl, so that a mis-built or mis-linked liboqs cannot
+// produce the vectors it is checked against. The transcripts follow the
+// kat_kem and kat_sig programs of liboqs, with the randomness drawn from the
+// NIST AES-CTR DRBG in the same order as liboqs draws it. Run it from its
+// directory:
+//
+//	go run .
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem512"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+)
+
+// katDir is the directory of the vectors.
+var katDir = flag.String("dir", "../testdata/kat", "output directory")
+
+// nistDRBG is the AES-256 CTR DRBG of the NIST PQC KAT generators, see
+// oqstests/kat_test.go.
+type nistDRBG struct {
+	key [32]byte
+	v   [aes.BlockSize]byte
+}
+
+func newNISTDRBG(entropy []byte) *nistDRBG {
+	d := &nistDRBG{}
+	d.update(entropy)
+	return d
+}
+
+func (d *nistDRBG) increment() {
+	for j := len(d.v) - 1; j >= 0; j-- {
+		d.v[j]++
+		if d.v[j] != 0 {
+			break
+		}
+	}
+}
+
+func (d *nistDRBG) update(providedData []byte) {
+	block, _ := aes.NewCipher(d.key[:])
+	var temp [48]byte
+	for i := 0; i < 3; i++ {
+		d.increment()
+		block.Encrypt(temp[16*i:], d.v[:])
+	}
+	for i := range providedData {
+		temp[i] ^= providedData[i]
+	}
+	copy(d.key[:], temp[:32])
+	copy(d.v[:], temp[32:])
+}
+
+func (d *nistDRBG) Read(p []byte) (int, error) {
+	block, _ := aes.NewCipher(d.key[:])
+	var out [aes.BlockSize]byte
+	for i := 0; i < len(p); i += aes.BlockSize {
+		d.increment()
+		block.Encrypt(out[:], d.v[:])
+		copy(p[i:], out[:])
+	}
+	d.update(nil)
+	return len(p), nil
+}
+
+// draw returns the next n bytes of the DRBG, as randombytes(buf, n) does.
+func (d *nistDRBG) draw(n int) []byte {
+	b := make([]byte, n)
+	_, _ = d.Read(b)
+	return b
+}
+
+// katSeed returns the DRBG seeded with the entropy input 0, 1, ..., 47.
+func katSeed() *nistDRBG {
+	entropy := make([]byte, 48)
+	for i := range entropy {
+		entropy[i] = byte(i)
+	}
+	return newNISTDRBG(entropy)
+}
+
+func fprintBstr(w io.Writer, label string, data []byte) {
+	if len(data) == 0 {
+		fmt.Fprintf(w, "%s00\n", label)
+		return
+	}
+	fmt.Fprintf(w, "%s%X\n", label, data)
+}
+
+// kemTranscript follows kat_kem: the key pair draws the 64-byte seed d || z,
+// and the encapsulation the 32-byte message m.
+func kemTranscript(scheme kem.Scheme) ([]byte, error) {
+	var rsp bytes.Buffer
+	seed := katSeed().draw(48)
+	fmt.Fprintf(&rsp, "count = 0\n")
+	fprintBstr(&rsp, "seed = ", seed)
+
+	drbg := newNISTDRBG(seed)
+	pk, sk := scheme.DeriveKeyPair(drbg.draw(scheme.SeedSize()))
+	publicKey, _ := pk.MarshalBinary()
+	secretKey, _ := sk.MarshalBinary()
+	fprintBstr(&rsp, "pk = ", publicKey)
+	fprintBstr(&rsp, "sk = ", secretKey)
+	ct, ss, err := scheme.EncapsulateDeterministically(pk,
+		drbg.draw(scheme.EncapsulationSeedSize()))
+	if err != nil {
+		return nil, err
+	}
+	fprintBstr(&rsp, "ct = ", ct)
+	fprintBstr(&rsp, "ss = ", ss)
+	return rsp.Bytes(), nil
+}
+
+// mldsa is the subset of the circl ML-DSA API used by sigTranscript.
+type mldsa struct {
+	keyPair func(seed []byte) (publicKey, secretKey []byte)
+	sign    func(secretKey, msg []byte) ([]byte, error)
+}
+
+// sigTranscript follows kat_sig: the key pair draws the 32-byte seed xi, and
+// the hedged signature the 32-byte rnd, which circl reads from crypto/rand.
+// The signed message of ML-DSA is the signature followed by the message.
+func sigTranscript(scheme mldsa) ([]byte, error) {
+	var rsp bytes.Buffer
+	kat := katSeed()
+	seed := kat.draw(48)
+	fmt.Fprintf(&rsp, "count = 0\n")
+	fprintBstr(&rsp, "seed = ", seed)
+	msg := kat.draw(33)
+	fmt.Fprintf(&rsp, "mlen = %d\n", len(msg))
+	fprintBstr(&rsp, "msg = ", msg)
+
+	drbg := newNISTDRBG(seed)
+	publicKey, secretKey := scheme.keyPair(drbg.draw(32))
+	fprintBstr(&rsp, "pk = ", publicKey)
+	fprintBstr(&rsp, "sk = ", secretKey)
+	systemRand := rand.Reader
+	rand.Reader = bytes.NewReader(drbg.draw(32))
+	signature, err := scheme.sign(secretKey, msg)
+	rand.Reader = systemRand
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(&rsp, "smlen = %d\n", len(signature)+len(msg))
+	fprintBstr(&rsp, "sm = ", append(signature, msg...))
+	return rsp.Bytes(), nil
+}
+
+// writeKATs writes the gzip-compressed transcripts and their kats.json
+// SHA-256 digests of kind, i.e. "kem" or "sig".
+func writeKATs(kind string, transcripts map[string][]byte) error {
+	dir := filepath.Join(*katDir, kind)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	digests := make(map[string]string)
+	for algName, rsp := range transcripts {
+		var buf bytes.Buffer
+		w, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		_, _ = w.Write(rsp)
+		if err := w.Close(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, algName+".rsp.gz"),
+			buf.Bytes(), 0o644); err != nil {
+			return err
+		}
+		digest := sha256.Sum256(rsp)
+		digests[algName] = hex.EncodeToString(digest[:])
+	}
+	data, err := json.MarshalIndent(digests, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "kats.json"), append(data, '\n'),
+		0o644)
+}
+
+func main() {
+	flag.Parse()
+	kems := map[string]kem.Scheme{
+		"ML-KEM-512":  mlkem512.Scheme(),
+		"ML-KEM-768":  mlkem768.Scheme(),
+		"ML-KEM-1024": mlkem1024.Scheme(),
+	}
+	transcripts := make(map[string][]byte)
+	for algName, scheme := range kems {
+		rsp, err := kemTranscript(scheme)
+		if err != nil {
+			log.Fatalf("%s: %v", algName, err)
+		}
+		transcripts[algName] = rsp
+	}
+	if err := writeKATs("kem", transcripts); err != nil {
+		log.Fatal(err)
+	}
+
+	sigs := map[string]mldsa{
+		"ML-DSA-44": {
+			keyPair: func(seed []byte) ([]byte, []byte) {
+				pk, sk := mldsa44.NewKeyFromSeed((*[mldsa44.SeedSize]byte)(seed))
+				return pk.Bytes(), sk.Bytes()
+			},
+			sign: func(secretKey, msg []byte) ([]byte, error) {
+				var sk mldsa44.PrivateKey
+				sk.Unpack((*[mldsa44.PrivateKeySize]byte)(secretKey))
+				signature := make([]byte, mldsa44.SignatureSize)
+				return signature, mldsa44.SignTo(&sk, msg, nil, true, signature)
+			},
+		},
+		"ML-DSA-65": {
+			keyPair: func(seed []byte) ([]byte, []byte) {
+				pk, sk := mldsa65.NewKeyFromSeed((*[mldsa65.SeedSize]byte)(seed))
+				return pk.Bytes(), sk.Bytes()
+			},
+			sign: func(secretKey, msg []byte) ([]byte, error) {
+				var sk mldsa65.PrivateKey
+				sk.Unpack((*[mldsa65.PrivateKeySize]byte)(secretKey))
+				signature := make([]byte, mldsa65.SignatureSize)
+				return signature, mldsa65.SignTo(&sk, msg, nil, true, signature)
+			},
+		},
+		"ML-DSA-87": {
+			keyPair: func(seed []byte) ([]byte, []byte) {
+				pk, sk := mldsa87.NewKeyFromSeed((*[mldsa87.SeedSize]byte)(seed))
+				return pk.Bytes(), sk.Bytes()
+			},
+			sign: func(secretKey, msg []byte) ([]byte, error) {
+				var sk mldsa87.PrivateKey
+				sk.Unpack((*[mldsa87.PrivateKeySize]byte)(secretKey))
+				signature := make([]byte, mldsa87.SignatureSize)
+				return signature, mldsa87.SignTo(&sk, msg, nil, true, signature)
+			},
+		},
+	}
+	transcripts = make(map[string][]byte)
+	for algName, scheme := range sigs {
+		rsp, err := sigTranscript(scheme)
+		if err != nil {
+			log.Fatalf("%s: %v", algName, err)
+		}
+		transcripts[algName] = rsp
+	}
+	if err := writeKATs("sig", transcripts); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -3,6 +3,7 @@ package oqstests
 
 import (
 	"bytes"
+	"errors"
 	"log"
 	"runtime"
 	"sync"
@@ -150,3 +151,62 @@ func TestUnsupportedKeyEncapsulation(t *testing.T) {
 		t.Errorf("Unsupported KEM should have emitted an error")
 	}
 }
+
+// TestNewKeyEncapsulation tests the constructor-style API and that Clean can
+// be safely invoked more than once.
+func TestNewKeyEncapsulation(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		client, err := oqs.NewKeyEncapsulation(kemName)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		clientPublicKey, _ := client.GenerateKeyPair()
+		server, err := oqs.NewKeyEncapsulation(kemName,
+			oqs.WithSecretKey(client.ExportSecretKey()))
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		ciphertext, sharedSecretClient, _ := client.EncapSecret(clientPublicKey)
+		sharedSecretServer, _ := server.DecapSecret(ciphertext)
+		if !bytes.Equal(sharedSecretClient, sharedSecretServer) {
+			t.Errorf("%s: shared secrets do not coincide", kemName)
+		}
+		server.Clean()
+		client.Clean()
+		client.Clean()
+	}
+	if _, err := oqs.NewKeyEncapsulation("unsupported_kem"); err == nil {
+		t.Errorf("Unsupported KEM should have emitted an error")
+	}
+}
+
+// TestKeyEncapsulationErrors tests that failures are reported as typed errors.
+func TestKeyEncapsulationErrors(t *testing.T) {
+	var uninitialized oqs.KeyEncapsulation
+	if _, err := uninitialized.GenerateKeyPair(); !errors.Is(err, oqs.ErrNotInitialized) {
+		t.Errorf("Expected ErrNotInitialized, got %v", err)
+	}
+	if _, err := oqs.NewKeyEncapsulation("unsupported_kem"); !errors.Is(err, oqs.ErrAlgorithmNotSupported) {
+		t.Errorf("Expected ErrAlgorithmNotSupported, got %v", err)
+	}
+	if _, err := oqs.KEMName(oqs.MaxNumberKEMs()); !errors.Is(err, oqs.ErrAlgorithmIDOutOfRange) {
+		t.Errorf("Expected ErrAlgorithmIDOutOfRange, got %v", err)
+	}
+	for _, kemName := range oqs.EnabledKEMs() {
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		details := kem.Details()
+		_, _, err := kem.EncapSecret(make([]byte, details.LengthPublicKey+1))
+		var oqsErr *oqs.Error
+		if !errors.As(err, &oqsErr) || oqsErr.Alg != kemName ||
+			!errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: expected ErrInvalidPublicKeyLength, got %v", kemName, err)
+		}
+		if _, err := kem.DecapSecret(make([]byte, details.LengthCiphertext)); !errors.Is(err, oqs.ErrNoSecretKey) {
+			t.Errorf("%s: expected ErrNoSecretKey, got %v", kemName, err)
+		}
+		if _, err := kem.DecapSecret(nil); !errors.Is(err, oqs.ErrInvalidCiphertextLength) {
+			t.Errorf("%s: expected ErrInvalidCiphertextLength, got %v", kemName, err)
+		}
+		kem.Clean()
+	}
+}
@@ -0,0 +1,71 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestKEMPrivateKey tests the KEM key types of all enabled KEMs.
+func TestKEMPrivateKey(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		log.Println("KEM keys - ", kemName)
+		priv, err := oqs.GenerateKEMKey(kemName)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		pub := priv.Public().(*oqs.KEMPublicKey)
+		if pub.Algorithm() != kemName || priv.Algorithm() != kemName {
+			t.Errorf("%s: keys do not carry their algorithm name", kemName)
+		}
+		ciphertext, sharedSecretSender, err := pub.Encapsulate()
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		sharedSecretRecipient, err := priv.Decapsulate(ciphertext)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		if !bytes.Equal(sharedSecretSender, sharedSecretRecipient) {
+			t.Errorf("%s: shared secrets do not coincide", kemName)
+		}
+		if _, err := priv.Decapsulate(ciphertext[1:]); !errors.Is(err, oqs.ErrInvalidCiphertextLength) {
+			t.Errorf("%s: expected ErrInvalidCiphertextLength, got %v", kemName, err)
+		}
+
+		secretKey := priv.ExportSecretKey()
+		imported, err := oqs.NewKEMPrivateKey(kemName, secretKey, pub.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		if !imported.Equal(priv) || !imported.Public().(*oqs.KEMPublicKey).Equal(pub) {
+			t.Errorf("%s: imported key pair should be equal", kemName)
+		}
+		imported.Clean()
+		if _, err := oqs.NewKEMPrivateKey(kemName, secretKey[1:], pub.Bytes()); !errors.Is(err, oqs.ErrInvalidSecretKeyLength) {
+			t.Errorf("%s: expected ErrInvalidSecretKeyLength, got %v", kemName, err)
+		}
+		if _, err := oqs.NewKEMPrivateKey(kemName, secretKey, pub.Bytes()[1:]); !errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: expected ErrInvalidPublicKeyLength, got %v", kemName, err)
+		}
+		other, _ := oqs.GenerateKEMKey(kemName)
+		if _, err := oqs.NewKEMPrivateKey(kemName, secretKey, other.Public().(*oqs.KEMPublicKey).Bytes()); !errors.Is(err, oqs.ErrKeyPairMismatch) {
+			t.Errorf("%s: expected ErrKeyPairMismatch, got %v", kemName, err)
+		}
+		other.Clean()
+
+		if _, err := oqs.AlgorithmOID(kemName); err == nil {
+			der, err := oqs.MarshalPKCS8PrivateKey(priv)
+			if err != nil {
+				t.Fatalf("%s: %v", kemName, err)
+			}
+			if _, err := oqs.ParsePKCS8PrivateKey(der); err != nil {
+				t.Errorf("%s: %v", kemName, err)
+			}
+		}
+		priv.Clean()
+	}
+}
@@ -0,0 +1,97 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestKeyEncapsulationKeyPair tests the public key derivation and the key
+// pair consistency check of all enabled KEMs.
+func TestKeyEncapsulationKeyPair(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		log.Println("KEM key pair - ", kemName)
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		publicKey, _ := kem.GenerateKeyPair()
+		other, _ := oqs.NewKeyEncapsulation(kemName)
+		otherPublicKey, _ := other.GenerateKeyPair()
+		other.Clean()
+
+		imported, _ := oqs.NewKeyEncapsulation(kemName,
+			oqs.WithSecretKey(append([]byte(nil), kem.ExportSecretKey()...)))
+		derived, err := imported.PublicKey()
+		if strings.HasPrefix(kemName, "ML-KEM-") {
+			if err != nil || !bytes.Equal(derived, publicKey) {
+				t.Errorf("%s: derived public key does not coincide (%v)", kemName, err)
+			}
+		} else if !errors.Is(err, oqs.ErrPublicKeyUnavailable) {
+			t.Errorf("%s: expected ErrPublicKeyUnavailable, got %v", kemName, err)
+		}
+
+		if err := imported.ValidateKeyPair(publicKey); err != nil {
+			t.Errorf("%s: %v", kemName, err)
+		}
+		if err := imported.ValidateKeyPair(otherPublicKey); !errors.Is(err, oqs.ErrKeyPairMismatch) {
+			t.Errorf("%s: expected ErrKeyPairMismatch, got %v", kemName, err)
+		}
+		imported.Clean()
+		kem.Clean()
+	}
+}
+
+// TestSignatureKeyPair tests the public key derivation and the key pair
+// consistency check of all enabled signatures.
+func TestSignatureKeyPair(t *testing.T) {
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("Signature key pair - ", sigName)
+		signer, _ := oqs.NewSignature(sigName)
+		publicKey, _ := signer.GenerateKeyPair()
+		other, _ := oqs.NewSignature(sigName)
+		otherPublicKey, _ := other.GenerateKeyPair()
+		other.Clean()
+
+		imported, _ := oqs.NewSignature(sigName,
+			oqs.WithSecretKey(append([]byte(nil), signer.ExportSecretKey()...)))
+		derived, err := imported.PublicKey()
+		if strings.HasPrefix(sigName, "SLH_DSA_") || strings.HasPrefix(sigName, "ML-DSA-") {
+			if err != nil || !bytes.Equal(derived, publicKey) {
+				t.Errorf("%s: derived public key does not coincide (%v)", sigName, err)
+			}
+		} else if !errors.Is(err, oqs.ErrPublicKeyUnavailable) {
+			t.Errorf("%s: expected ErrPublicKeyUnavailable, got %v", sigName, err)
+		}
+
+		if err := imported.ValidateKeyPair(publicKey); err != nil {
+			t.Errorf("%s: %v", sigName, err)
+		}
+
+		// An ML-DSA public key recomputed from rho, s1 and s2 is checked
+		// against the hash tr embedded in the secret key.
+		if strings.HasPrefix(sigName, "ML-DSA-") {
+			secretKey := signer.ExportSecretKey()
+			secretKey[64] ^= 1
+			corrupted, _ := oqs.NewSignature(sigName, oqs.WithSecretKey(secretKey))
+			if _, err := corrupted.PublicKey(); !errors.Is(err, oqs.ErrKeyPairMismatch) {
+				t.Errorf("%s: expected ErrKeyPairMismatch, got %v", sigName, err)
+			}
+			corrupted.Clean()
+		}
+		if err := imported.ValidateKeyPair(otherPublicKey); !errors.Is(err, oqs.ErrKeyPairMismatch) {
+			t.Errorf("%s: expected ErrKeyPairMismatch, got %v", sigName, err)
+		}
+		imported.Clean()
+
+		// ML-DSA public keys are regenerated from the seed.
+		if seedLength := signer.Details().LengthKeypairSeed; seedLength != 0 {
+			publicKey, _ := signer.GenerateKeyPairFromSeed(oqs.RandomBytes(seedLength))
+			if derived, err := signer.PublicKey(); err != nil || !bytes.Equal(derived, publicKey) {
+				t.Errorf("%s: derived public key does not coincide (%v)", sigName, err)
+			}
+		}
+		signer.Clean()
+	}
+}
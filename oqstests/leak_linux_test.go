@@ -0,0 +1,204 @@
+//go:build linux
+
+package oqstests
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// residentSetSize returns the resident set size of the process, in bytes,
+// which includes the C heap.
+func residentSetSize(t *testing.T) int {
+	statm, err := os.ReadFile("/proc/self/statm")
+	if err != nil {
+		t.Skip(err)
+	}
+	fields := strings.Fields(string(statm))
+	pages, err := strconv.Atoi(fields[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pages * os.Getpagesize()
+}
+
+// checkResidentSetGrowth runs many cycles and checks that the resident set
+// size does not grow with them.
+func checkResidentSetGrowth(t *testing.T, what string, cycle func()) {
+	// Warm up the allocators, then measure.
+	for i := 0; i < 1<<14; i++ {
+		cycle()
+	}
+	runtime.GC()
+	before := residentSetSize(t)
+	const cycles = 1 << 20
+	for i := 0; i < cycles; i++ {
+		cycle()
+	}
+	runtime.GC()
+	after := residentSetSize(t)
+
+	// Leaking a single C string or liboqs object per cycle would grow by more
+	// than 16 MiB.
+	if growth := after - before; growth > 8<<20 {
+		t.Errorf("%s: resident set size grew by %d bytes over %d cycles", what,
+			growth, cycles)
+	}
+}
+
+// TestAlgorithmNameLeak runs many Init/Clean cycles and algorithm lookups,
+// which pass the algorithm name to liboqs as a C string, then many Init
+// cycles on the same objects, which replace their liboqs objects, and checks
+// that the resident set size does not grow with them.
+func TestAlgorithmNameLeak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the leak test in short mode")
+	}
+	kemNames, sigNames := oqs.EnabledKEMs(), oqs.EnabledSigs()
+	if len(kemNames) == 0 || len(sigNames) == 0 {
+		t.Skip("no KEM or no signature is enabled")
+	}
+	kemName, sigName := kemNames[0], sigNames[0]
+	checkResidentSetGrowth(t, "Init/Clean", func() {
+		var kem oqs.KeyEncapsulation
+		var sig oqs.Signature
+		_ = kem.Init(kemName, nil)
+		kem.Clean()
+		_ = sig.Init(sigName, nil)
+		sig.Clean()
+		oqs.IsKEMEnabled(kemName)
+		oqs.IsSigEnabled(sigName)
+		oqs.IsStatefulSigEnabled("XMSS-SHA2_10_256")
+		_ = oqs.RandomBytesSwitchAlgorithm("system")
+	})
+
+	var kem oqs.KeyEncapsulation
+	defer kem.Clean()
+	var sig oqs.Signature
+	defer sig.Clean()
+	checkResidentSetGrowth(t, "re-Init", func() {
+		_ = kem.Init(kemName, nil)
+		_ = sig.Init(sigName, nil)
+	})
+}
+
+// memoryMappings returns the number of memory mappings of the process.
+func memoryMappings(t *testing.T) int {
+	maps, err := os.ReadFile("/proc/self/maps")
+	if err != nil {
+		t.Skip(err)
+	}
+	return strings.Count(string(maps), "\n")
+}
+
+// TestSecretBufferMappings checks that small secret buffers share the memory
+// mappings of a few arenas, rather than getting a mapping each.
+func TestSecretBufferMappings(t *testing.T) {
+	mappings := func() int { return memoryMappings(t) }
+	before := mappings()
+	bufs := make([]*oqs.SecretBuffer, 10000)
+	for i := range bufs {
+		bufs[i], _ = oqs.NewSecretBuffer(32)
+	}
+	after := mappings()
+	for _, buf := range bufs {
+		buf.Destroy()
+	}
+
+	// A mapping per buffer would add at least 10000 mappings, whereas the
+	// arenas of 1024 slots of 64 bytes add at most 3 mappings each.
+	if growth := after - before; growth > 100 {
+		t.Errorf("%d secret buffers added %d memory mappings", len(bufs), growth)
+	}
+}
+
+// TestSecretBufferFinalizer checks that the finalizer of the secret buffers
+// that are not destroyed returns their slots, and unmaps the emptied arenas.
+func TestSecretBufferFinalizer(t *testing.T) {
+	before := memoryMappings(t)
+	func() {
+		// 16 slots of 4 KiB per arena, hence 1250 arenas.
+		bufs := make([]*oqs.SecretBuffer, 20000)
+		for i := range bufs {
+			bufs[i], _ = oqs.NewSecretBuffer(4096)
+		}
+		if growth := memoryMappings(t) - before; growth < 1000 {
+			t.Fatalf("%d secret buffers added only %d memory mappings",
+				len(bufs), growth)
+		}
+	}()
+	growth := 0
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+		// The arena kept with free slots and the growth of the Go heap add
+		// a few mappings.
+		if growth = memoryMappings(t) - before; growth <= 100 {
+			return
+		}
+	}
+	t.Errorf("unreachable secret buffers still hold %d memory mappings", growth)
+}
+
+// TestSecretBufferGuardPages checks, in a child process, that a write past the
+// end of a dedicated secret mapping or of an arena faults on its guard page,
+// while a write past the end of a slot in the middle of an arena reaches the
+// next slot: the slots of an arena deliberately share its guard pages.
+func TestSecretBufferGuardPages(t *testing.T) {
+	if overflow := os.Getenv("OQS_TEST_SECRET_OVERFLOW"); overflow != "" {
+		overflowSecretBuffer(overflow)
+		return
+	}
+	for _, overflow := range []string{"dedicated", "arena"} {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestSecretBufferGuardPages$")
+		cmd.Env = append(os.Environ(), "OQS_TEST_SECRET_OVERFLOW="+overflow)
+		out, err := cmd.CombinedOutput()
+		if err == nil || !strings.Contains(string(out), "unexpected fault address") {
+			t.Errorf("%s: write past the end did not fault: %v\n%s", overflow,
+				err, out)
+		}
+		if overflow == "arena" && !strings.Contains(string(out), "slot overflow") {
+			t.Errorf("%s: write past the end of a slot faulted\n%s", overflow, out)
+		}
+	}
+}
+
+// overflowSecretBuffer writes a byte past the end of secret buffers, which
+// crashes the process on a guard page.
+func overflowSecretBuffer(overflow string) {
+	writePast := func(data []byte) {
+		end := unsafe.Add(unsafe.Pointer(unsafe.SliceData(data)), len(data))
+		*(*byte)(end) = 1
+	}
+	if overflow == "dedicated" {
+		buf, _ := oqs.NewSecretBuffer(20000)
+		writePast(buf.Bytes())
+		return
+	}
+
+	// The 4 slots of 16 KiB of an arena, the last of which ends at its
+	// trailing guard page.
+	slots := make([][]byte, 4)
+	for i := range slots {
+		buf, _ := oqs.NewSecretBuffer(16 << 10)
+		slots[i] = buf.Bytes()
+		defer runtime.KeepAlive(buf)
+	}
+	sort.Slice(slots, func(i, j int) bool {
+		return uintptr(unsafe.Pointer(&slots[i][0])) <
+			uintptr(unsafe.Pointer(&slots[j][0]))
+	})
+	writePast(slots[0])
+	fmt.Println("slot overflow")
+	writePast(slots[len(slots)-1])
+}
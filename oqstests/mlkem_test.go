@@ -0,0 +1,68 @@
+package oqstests
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestMLKEMValidation tests the FIPS 203 input checks of ML-KEM.
+func TestMLKEMValidation(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		if !strings.HasPrefix(kemName, "ML-KEM-") {
+			continue
+		}
+		log.Println("ML-KEM validation - ", kemName)
+		client, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithStrictValidation())
+		publicKey, _ := client.GenerateKeyPair()
+		if err := client.ValidatePublicKey(publicKey); err != nil {
+			t.Errorf("%s: %v", kemName, err)
+		}
+		if err := client.ValidateSecretKey(); err != nil {
+			t.Errorf("%s: %v", kemName, err)
+		}
+		ciphertext, _, err := client.EncapSecret(publicKey)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		if _, err := client.DecapSecret(ciphertext); err != nil {
+			t.Errorf("%s: %v", kemName, err)
+		}
+
+		// A coefficient of 0xfff is not reduced modulo q = 3329.
+		unreduced := append([]byte(nil), publicKey...)
+		unreduced[0], unreduced[1] = 0xff, unreduced[1]|0x0f
+		if err := client.ValidatePublicKey(unreduced); !errors.Is(err, oqs.ErrInvalidPublicKey) {
+			t.Errorf("%s: expected ErrInvalidPublicKey, got %v", kemName, err)
+		}
+		if _, _, err := client.EncapSecret(unreduced); !errors.Is(err, oqs.ErrInvalidPublicKey) {
+			t.Errorf("%s: expected ErrInvalidPublicKey in strict mode, got %v", kemName, err)
+		}
+		if err := client.ValidatePublicKey(publicKey[1:]); !errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: expected ErrInvalidPublicKeyLength, got %v", kemName, err)
+		}
+		// Without strict validation, the public key is passed to liboqs as is;
+		// recent liboqs releases perform the modulus check themselves.
+		lenient, _ := oqs.NewKeyEncapsulation(kemName)
+		if _, _, err := lenient.EncapSecret(unreduced); errors.Is(err, oqs.ErrInvalidPublicKey) {
+			t.Errorf("%s: lenient mode validated the public key: %v", kemName, err)
+		}
+		lenient.Clean()
+
+		// Corrupt the embedded hash H(ek) of the secret key.
+		secretKey := append([]byte(nil), client.ExportSecretKey()...)
+		secretKey[len(secretKey)-33] ^= 1
+		corrupted, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithSecretKey(secretKey), oqs.WithStrictValidation())
+		if err := corrupted.ValidateSecretKey(); !errors.Is(err, oqs.ErrInvalidSecretKey) {
+			t.Errorf("%s: expected ErrInvalidSecretKey, got %v", kemName, err)
+		}
+		if _, err := corrupted.DecapSecret(ciphertext); !errors.Is(err, oqs.ErrInvalidSecretKey) {
+			t.Errorf("%s: expected ErrInvalidSecretKey in strict mode, got %v", kemName, err)
+		}
+		corrupted.Clean()
+		client.Clean()
+	}
+}
@@ -0,0 +1,156 @@
+package oqstests
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestPKIXKeyEncapsulation tests the SubjectPublicKeyInfo and PKCS#8
+// encodings of all enabled KEMs that have an OID.
+func TestPKIXKeyEncapsulation(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		if _, err := oqs.AlgorithmOID(kemName); err != nil {
+			continue
+		}
+		log.Println("PKIX - ", kemName)
+		client, _ := oqs.NewKeyEncapsulation(kemName)
+		publicKey, _ := client.GenerateKeyPair()
+		pub, _ := oqs.NewKEMPublicKey(kemName, publicKey)
+
+		pubPEM, err := oqs.EncodePublicKeyPEM(pub)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		parsedPub, err := oqs.DecodePublicKeyPEM(pubPEM)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		if !pub.Equal(parsedPub) {
+			t.Errorf("%s: parsed public key does not coincide", kemName)
+		}
+
+		privPEM, err := oqs.EncodePrivateKeyPEM(client)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		parsed, err := oqs.DecodePrivateKeyPEM(privPEM)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		server := parsed.(*oqs.KeyEncapsulation)
+		ciphertext, sharedSecretClient, _ := client.EncapSecret(publicKey)
+		sharedSecretServer, _ := server.DecapSecret(ciphertext)
+		if !bytes.Equal(sharedSecretClient, sharedSecretServer) {
+			t.Errorf("%s: shared secrets do not coincide", kemName)
+		}
+		if _, err := oqs.MarshalPKCS8PrivateKeyFormat(client, oqs.PrivateKeySeed); !errors.Is(err, oqs.ErrNoSeed) {
+			t.Errorf("%s: expected ErrNoSeed, got %v", kemName, err)
+		}
+
+		// An ML-KEM expanded key whose embedded H(ek) does not match ek is
+		// rejected at parse time.
+		if strings.HasPrefix(kemName, "ML-KEM-") {
+			der, _ := oqs.MarshalPKCS8PrivateKeyFormat(client, oqs.PrivateKeyExpanded)
+			secretKey := client.ExportSecretKey()
+			i := bytes.Index(der, secretKey) + len(secretKey) - 64
+			der[i] ^= 1
+			if _, err := oqs.ParsePKCS8PrivateKey(der); !errors.Is(err, oqs.ErrInvalidSecretKey) {
+				t.Errorf("%s: expected ErrInvalidSecretKey, got %v", kemName, err)
+			}
+		}
+		server.Clean()
+		client.Clean()
+	}
+}
+
+// TestPKIXSignature tests the SubjectPublicKeyInfo and PKCS#8 encodings of
+// all enabled signatures that have an OID, and of the composite signatures.
+func TestPKIXSignature(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range append(oqs.EnabledSigs(), oqs.SupportedCompositeSigs()...) {
+		if _, err := oqs.AlgorithmOID(sigName); err != nil {
+			continue
+		}
+		log.Println("PKIX - ", sigName)
+		key, err := oqs.GenerateSigKey(sigName)
+		if err != nil {
+			composite, err := oqs.GenerateCompositeKey(sigName)
+			if errors.Is(err, oqs.ErrAlgorithmNotEnabled) {
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+			testPKIXCompositeSignature(composite, msg, t)
+			composite.Clean()
+			continue
+		}
+
+		der, err := oqs.MarshalPKIXPublicKey(key.Public())
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		parsedPub, err := oqs.ParsePKIXPublicKey(der)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		der, err = oqs.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		parsed, err := oqs.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		signer := parsed.(*oqs.Signature)
+		signature, _ := signer.Sign(msg)
+		if isValid, _ := parsedPub.(*oqs.SigPublicKey).Verify(msg, signature, nil); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		signer.Clean()
+		key.Clean()
+	}
+}
+
+// testPKIXCompositeSignature tests the encodings of a composite signature key.
+func testPKIXCompositeSignature(key *oqs.CompositePrivateKey, msg []byte, t *testing.T) {
+	sigName := key.Algorithm()
+	der, err := oqs.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatalf("%s: %v", sigName, err)
+	}
+	parsedPub, err := oqs.ParsePKIXPublicKey(der)
+	if err != nil {
+		t.Fatalf("%s: %v", sigName, err)
+	}
+	der, err = oqs.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("%s: %v", sigName, err)
+	}
+	parsed, err := oqs.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatalf("%s: %v", sigName, err)
+	}
+	signer := parsed.(*oqs.CompositePrivateKey)
+	signature, _ := signer.Sign(nil, msg, nil)
+	if isValid, _ := parsedPub.(*oqs.CompositePublicKey).Verify(msg, signature, nil); !isValid {
+		t.Errorf("%s: signature verification failed", sigName)
+	}
+	signer.Clean()
+}
+
+// TestPKIXUnknownOID tests that an unknown OID emits an error.
+func TestPKIXUnknownOID(t *testing.T) {
+	if _, err := oqs.AlgorithmName(asn1.ObjectIdentifier{1, 2, 3}); !errors.Is(err, oqs.ErrUnknownOID) {
+		t.Errorf("Expected ErrUnknownOID, got %v", err)
+	}
+	if _, err := oqs.ParsePKIXPublicKey([]byte("not DER")); !errors.Is(err, oqs.ErrInvalidPublicKey) {
+		t.Errorf("Expected ErrInvalidPublicKey, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestKEMPool tests the recycling of KEMs.
+func TestKEMPool(t *testing.T) {
+	pool := oqs.NewKEMPool(2, 0)
+	defer pool.Close()
+	for _, kemName := range oqs.EnabledKEMs() {
+		log.Println("KEM pool - ", kemName)
+		client, err := pool.Get(kemName)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		publicKey, _ := client.GenerateKeyPair()
+		server, _ := pool.Get(kemName)
+		ciphertext, sharedSecretServer, _ := server.EncapSecret(publicKey)
+		sharedSecretClient, _ := client.DecapSecret(ciphertext)
+		if !bytes.Equal(sharedSecretClient, sharedSecretServer) {
+			t.Errorf("%s: shared secrets do not coincide", kemName)
+		}
+
+		if err := pool.Put(client); err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		if client.ExportSecretKey() != nil {
+			t.Errorf("%s: secret key was not destroyed on return", kemName)
+		}
+		if _, err := client.DecapSecret(ciphertext); !errors.Is(err, oqs.ErrNotInitialized) {
+			t.Errorf("%s: expected ErrNotInitialized after return, got %v", kemName, err)
+		}
+		if err := pool.Put(client); !errors.Is(err, oqs.ErrNotInitialized) {
+			t.Errorf("%s: expected ErrNotInitialized on double return, got %v", kemName, err)
+		}
+
+		reused, _ := pool.Get(kemName)
+		if reused == client {
+			t.Errorf("%s: returned handle was handed out again", kemName)
+		}
+		if _, err := reused.DecapSecret(ciphertext); !errors.Is(err, oqs.ErrNoSecretKey) {
+			t.Errorf("%s: expected ErrNoSecretKey, got %v", kemName, err)
+		}
+		if reused.Details() != server.Details() {
+			t.Errorf("%s: details do not coincide", kemName)
+		}
+		pool.Put(reused)
+		pool.Put(server)
+	}
+	stats := pool.Stats()
+	if stats.Hits == 0 || stats.Idle > 2*len(oqs.EnabledKEMs()) {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+}
+
+// TestSigPool tests the recycling of signatures, also concurrently.
+func TestSigPool(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	pool := oqs.NewSigPool(4, 4)
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("Signature pool - ", sigName)
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				signer, err := pool.Get(sigName)
+				if err != nil {
+					t.Errorf("%s: %v", sigName, err)
+					return
+				}
+				defer pool.Put(signer)
+				publicKey, _ := signer.GenerateKeyPair()
+				signature, _ := signer.Sign(msg)
+				if isValid, _ := signer.Verify(msg, signature, publicKey); !isValid {
+					t.Errorf("%s: signature verification failed", sigName)
+				}
+			}()
+		}
+		wg.Wait()
+		signer, _ := pool.Get(sigName)
+		if _, err := signer.Sign(msg); !errors.Is(err, oqs.ErrNoSecretKey) {
+			t.Errorf("%s: expected ErrNoSecretKey, got %v", sigName, err)
+		}
+		pool.Put(signer)
+	}
+	stats := pool.Stats()
+	if stats.Gets != stats.Puts || stats.Idle > 4*len(oqs.EnabledSigs()) {
+		t.Errorf("unexpected statistics %+v", stats)
+	}
+	pool.Close()
+	if stats := pool.Stats(); stats.Idle != 0 {
+		t.Errorf("closed pool still has %d idle handles", stats.Idle)
+	}
+}
+
+// TestPoolInUse tests the limit on the handles in use.
+func TestPoolInUse(t *testing.T) {
+	kemNames := oqs.EnabledKEMs()
+	if len(kemNames) == 0 {
+		t.Skip("no KEM is enabled")
+	}
+	kemName := kemNames[0]
+	pool := oqs.NewKEMPool(1, 1)
+	defer pool.Close()
+	first, _ := pool.Get(kemName)
+	got := make(chan *oqs.KeyEncapsulation)
+	go func() {
+		second, _ := pool.Get(kemName)
+		got <- second
+	}()
+	select {
+	case <-got:
+		t.Fatalf("%s: Get did not wait for a handle to be returned", kemName)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if stats := pool.Stats(); stats.InUse != 1 {
+		t.Errorf("%s: expected 1 handle in use, got %d", kemName, stats.InUse)
+	}
+	pool.Put(first)
+	second := <-got
+
+	// Clean, as the finalizer of a handle that is never returned, releases
+	// its place in the pool, once.
+	second.Clean()
+	second.Clean()
+	third, _ := pool.Get(kemName)
+	if stats := pool.Stats(); stats.InUse != 1 {
+		t.Errorf("%s: expected 1 handle in use, got %d", kemName, stats.InUse)
+	}
+	pool.Put(third)
+	if stats := pool.Stats(); stats.InUse != 0 {
+		t.Errorf("%s: expected no handle in use, got %d", kemName, stats.InUse)
+	}
+}
@@ -0,0 +1,351 @@
+package oqstests
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"errors"
+	"io"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestRand tests the io.Reader backed by OQS_randombytes.
+func TestRand(t *testing.T) {
+	if n, err := oqs.Rand.Read(nil); n != 0 || err != nil {
+		t.Errorf("zero-length read returned %d, %v", n, err)
+	}
+	if n, err := oqs.Rand.Read([]byte{}); n != 0 || err != nil {
+		t.Errorf("zero-length read returned %d, %v", n, err)
+	}
+
+	buf := make([]byte, 1000)
+	if _, err := io.ReadFull(oqs.Rand, buf); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(buf, make([]byte, len(buf))) {
+		t.Errorf("random bytes are all zero")
+	}
+
+	// Rand follows the algorithm selected for OQS_randombytes.
+	defer func() {
+		if err := oqs.RandomBytesSwitchAlgorithm("system"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	_ = oqs.RandomBytesCustomAlgorithm(func(randomArray []byte, bytesToRead int) {
+		for i := 0; i < bytesToRead; i++ {
+			randomArray[i] = 0x42
+		}
+	})
+	if _, err := io.ReadFull(oqs.Rand, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, bytes.Repeat([]byte{0x42}, len(buf))) {
+		t.Errorf("Rand does not use the custom RNG algorithm")
+	}
+}
+
+// TestRandomBytesEmpty tests the zero-length random byte and cleansing
+// requests.
+func TestRandomBytesEmpty(t *testing.T) {
+	if b := oqs.RandomBytes(0); len(b) != 0 {
+		t.Errorf("RandomBytes(0) returned %d bytes", len(b))
+	}
+	oqs.RandomBytesInPlace(nil, 16)
+	oqs.RandomBytesInPlace(make([]byte, 16), 0)
+	oqs.RandomBytesInPlace(make([]byte, 16), -1)
+	oqs.MemCleanse(nil)
+	oqs.MemCleanse([]byte{})
+}
+
+// TestRandReader tests Rand as a drop-in replacement of crypto/rand.Reader.
+func TestRandReader(t *testing.T) {
+	key, err := ecdh.X25519().GenerateKey(oqs.Rand)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := ecdh.X25519().GenerateKey(oqs.Rand)
+	if key.Equal(other) {
+		t.Errorf("generated keys coincide")
+	}
+}
+
+// deterministicRand returns a deterministic io.Reader seeded by seed.
+func deterministicRand(seed string) io.Reader {
+	shake := sha3.NewShake128()
+	_, _ = shake.Write([]byte(seed))
+	return shake
+}
+
+// failingReader is an io.Reader that always fails.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("entropy source unavailable")
+}
+
+// TestWithRand tests the per-operation RNG of KEMs and signatures.
+func TestWithRand(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		log.Println("KEM WithRand - ", kemName)
+		var publicKeys [2][]byte
+		var wg sync.WaitGroup
+		for i := range publicKeys {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				kem, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithRand(deterministicRand(kemName)))
+				defer kem.Clean()
+				publicKeys[i], _ = kem.GenerateKeyPair()
+			}(i)
+		}
+		wg.Wait()
+		if publicKeys[0] == nil || !bytes.Equal(publicKeys[0], publicKeys[1]) {
+			t.Errorf("%s: key pairs generated from the same reader do not coincide", kemName)
+		}
+		kem, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithRand(failingReader{}))
+		if publicKey, err := kem.GenerateKeyPair(); publicKey != nil || !errors.Is(err, oqs.ErrRandFailure) {
+			t.Errorf("%s: expected ErrRandFailure, got %v", kemName, err)
+		}
+		if _, _, err := kem.EncapSecret(publicKeys[0]); !errors.Is(err, oqs.ErrRandFailure) {
+			t.Errorf("%s: expected ErrRandFailure, got %v", kemName, err)
+		}
+		kem.Clean()
+	}
+
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("Signature WithRand - ", sigName)
+		signer, _ := oqs.NewSignature(sigName, oqs.WithRand(deterministicRand(sigName)))
+		publicKey, err := signer.GenerateKeyPair()
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		other, _ := oqs.NewSignature(sigName, oqs.WithRand(deterministicRand(sigName)))
+		if otherPublicKey, _ := other.GenerateKeyPair(); !bytes.Equal(publicKey, otherPublicKey) {
+			t.Errorf("%s: key pairs generated from the same reader do not coincide", sigName)
+		}
+		signature, err := signer.Sign(msg)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if isValid, _ := other.Verify(msg, signature, publicKey); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		other.Clean()
+		signer.Clean()
+	}
+}
+
+// TestWithRandConcurrent tests that a per-operation RNG neither affects nor is
+// affected by the global RNG used concurrently by other goroutines.
+func TestWithRandConcurrent(t *testing.T) {
+	kemName := oqs.EnabledKEMs()[0]
+	reference, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithRand(deterministicRand("concurrent")))
+	expected, _ := reference.GenerateKeyPair()
+	reference.Clean()
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		previous := oqs.RandomBytes(32)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			current := oqs.RandomBytes(32)
+			if bytes.Equal(previous, current) {
+				t.Errorf("global RNG repeated its output")
+				return
+			}
+			previous = current
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		kem, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithRand(deterministicRand("concurrent")))
+		if publicKey, _ := kem.GenerateKeyPair(); !bytes.Equal(publicKey, expected) {
+			t.Errorf("%s: key pair is affected by the global RNG", kemName)
+		}
+		kem.Clean()
+	}
+	close(done)
+	wg.Wait()
+}
+
+// exclusiveReader is an io.Reader that is not safe for concurrent use, and
+// reports concurrent reads, which the race detector does not see when they
+// happen in liboqs callbacks, as cgo calls synchronize all goroutines.
+type exclusiveReader struct {
+	t      *testing.T
+	r      io.Reader
+	inUse  atomic.Bool
+	shared int // written without synchronization, for -race
+}
+
+func (e *exclusiveReader) Read(b []byte) (int, error) {
+	if !e.inUse.CompareAndSwap(false, true) {
+		e.t.Errorf("reader used concurrently")
+		return 0, errors.New("reader used concurrently")
+	}
+	defer e.inUse.Store(false)
+	e.shared++
+	time.Sleep(10 * time.Microsecond)
+	return e.r.Read(b)
+}
+
+// TestWithRandSharedReader tests that the operations of different objects
+// sharing a reader that is not safe for concurrent use are serialized.
+func TestWithRandSharedReader(t *testing.T) {
+	kemName, sigName := oqs.EnabledKEMs()[0], oqs.EnabledSigs()[0]
+	r := &exclusiveReader{t: t, r: deterministicRand("shared")}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			kem, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithRand(r))
+			defer kem.Clean()
+			sig, _ := oqs.NewSignature(sigName, oqs.WithRand(r))
+			defer sig.Clean()
+			hybrid, err := oqs.NewHybridKeyEncapsulation("X25519MLKEM768",
+				oqs.WithRand(r))
+			if err == nil {
+				defer hybrid.Clean()
+			}
+			for j := 0; j < 20; j++ {
+				publicKey, err := kem.GenerateKeyPair()
+				if err != nil {
+					t.Errorf("%s: %v", kemName, err)
+					return
+				}
+				if _, _, err := kem.EncapSecret(publicKey); err != nil {
+					t.Errorf("%s: %v", kemName, err)
+					return
+				}
+				if _, err := sig.GenerateKeyPair(); err != nil {
+					t.Errorf("%s: %v", sigName, err)
+					return
+				}
+				if hybrid != nil {
+					if _, err := hybrid.GenerateKeyPair(); err != nil {
+						t.Errorf("X25519MLKEM768: %v", err)
+						return
+					}
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// TestWithRandCustomAlgorithm tests that per-operation RNGs leave the global
+// RNG algorithm selected by RandomBytesCustomAlgorithm in place for the other
+// goroutines, and that an unknown algorithm name is rejected.
+func TestWithRandCustomAlgorithm(t *testing.T) {
+	defer func() {
+		if err := oqs.RandomBytesSwitchAlgorithm("system"); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if err := oqs.RandomBytesCustomAlgorithm(benchmarkRNG); err != nil {
+		t.Fatal(err)
+	}
+	if err := oqs.RandomBytesSwitchAlgorithm("unknown"); err == nil {
+		t.Errorf("unknown RNG algorithm was accepted")
+	}
+	expected := make([]byte, 32)
+	benchmarkRNG(expected, len(expected))
+
+	kemName := oqs.EnabledKEMs()[0]
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			kem, _ := oqs.NewKeyEncapsulation(kemName, oqs.WithRand(deterministicRand("custom")))
+			_, _ = kem.GenerateKeyPair()
+			kem.Clean()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			wg.Wait()
+			return
+		default:
+		}
+		if random := oqs.RandomBytes(32); !bytes.Equal(random, expected) {
+			t.Fatalf("custom RNG algorithm was replaced by a per-operation RNG")
+		}
+	}
+}
+
+// benchmarkRNG is a cheap custom RNG algorithm, so that the benchmarks
+// measure the callback bridge rather than the RNG itself.
+func benchmarkRNG(randomArray []byte, bytesToRead int) {
+	for i := 0; i < bytesToRead; i++ {
+		randomArray[i] = byte(i)
+	}
+}
+
+// copyingRNG wraps an RNG algorithm the way the callback bridge used to
+// invoke it, i.e. into a freshly allocated slice that is then copied byte by
+// byte, to compare the former path with the zero-copy one.
+func copyingRNG(fun func([]byte, int)) func([]byte, int) {
+	return func(randomArray []byte, bytesToRead int) {
+		result := make([]byte, bytesToRead)
+		fun(result, bytesToRead)
+		for i, v := range result {
+			randomArray[i] = v
+		}
+	}
+}
+
+// benchmarkRandomBytesCustomAlgorithm benchmarks RandomBytesInPlace with a
+// custom RNG algorithm for several buffer sizes.
+func benchmarkRandomBytesCustomAlgorithm(b *testing.B, fun func([]byte, int)) {
+	defer func() {
+		if err := oqs.RandomBytesSwitchAlgorithm("system"); err != nil {
+			b.Fatal(err)
+		}
+	}()
+	if err := oqs.RandomBytesCustomAlgorithm(fun); err != nil {
+		b.Fatal(err)
+	}
+	for _, size := range []int{32, 1024, 65536} {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			buf := make([]byte, size)
+			b.SetBytes(int64(size))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				oqs.RandomBytesInPlace(buf, size)
+			}
+		})
+	}
+}
+
+// BenchmarkRandomBytesCustomAlgorithm benchmarks the zero-copy callback
+// bridge.
+func BenchmarkRandomBytesCustomAlgorithm(b *testing.B) {
+	benchmarkRandomBytesCustomAlgorithm(b, benchmarkRNG)
+}
+
+// BenchmarkRandomBytesCustomAlgorithmCopy benchmarks the former copying
+// callback bridge.
+func BenchmarkRandomBytesCustomAlgorithmCopy(b *testing.B) {
+	benchmarkRandomBytesCustomAlgorithm(b, copyingRNG(benchmarkRNG))
+}
@@ -0,0 +1,142 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"runtime"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestSecretBuffer tests the allocation and destruction of secret buffers.
+func TestSecretBuffer(t *testing.T) {
+	for _, size := range []int{1, 32, 4096, 10000} {
+		buf, err := oqs.NewSecretBuffer(size)
+		if err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		if !buf.Locked() {
+			log.Println("Secret buffer of", size, "bytes is not locked")
+		}
+		data := buf.Bytes()
+		if buf.Len() != size || len(data) != size || cap(data) != size {
+			t.Errorf("%d: buffer of %d bytes, capacity %d", size, len(data), cap(data))
+		}
+		if !bytes.Equal(data, make([]byte, size)) {
+			t.Errorf("%d: buffer is not zeroed", size)
+		}
+		oqs.RandomBytesInPlace(data, size)
+		buf.Destroy()
+		buf.Destroy()
+		if buf.Bytes() != nil || buf.Len() != 0 {
+			t.Errorf("%d: buffer is still accessible after Destroy", size)
+		}
+	}
+
+	if buf, err := oqs.NewSecretBuffer(0); err != nil || buf.Len() != 0 {
+		t.Errorf("empty buffer returned %v", err)
+	}
+	if _, err := oqs.NewSecretBuffer(-1); !errors.Is(err, oqs.ErrSecretMemory) {
+		t.Errorf("expected ErrSecretMemory, got %v", err)
+	}
+	var nilBuf *oqs.SecretBuffer
+	nilBuf.Destroy()
+	if nilBuf.Locked() {
+		t.Errorf("nil buffer is locked")
+	}
+
+	// The buffers sharing an arena do not overlap, and the slots of the
+	// destroyed ones are cleansed before being reused.
+	bufs := make([]*oqs.SecretBuffer, 3000)
+	for i := range bufs {
+		bufs[i], _ = oqs.NewSecretBuffer(100 + i%200)
+		for j := range bufs[i].Bytes() {
+			bufs[i].Bytes()[j] = byte(i)
+		}
+	}
+	for i, buf := range bufs {
+		if !bytes.Equal(buf.Bytes(), bytes.Repeat([]byte{byte(i)}, buf.Len())) {
+			t.Fatalf("buffer %d was overwritten", i)
+		}
+		if i%2 == 0 {
+			buf.Destroy()
+		}
+	}
+	for i := 0; i < len(bufs); i += 2 {
+		bufs[i], _ = oqs.NewSecretBuffer(300)
+		if !bytes.Equal(bufs[i].Bytes(), make([]byte, 300)) {
+			t.Fatalf("reused buffer %d is not zeroed", i)
+		}
+	}
+	for _, buf := range bufs {
+		buf.Destroy()
+	}
+}
+
+// TestKeyEncapsulationSecretBuffer tests the shared secrets returned in
+// secret buffers, and the secret keys kept in them.
+func TestKeyEncapsulationSecretBuffer(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		log.Println("Secret buffer - ", kemName)
+		client, _ := oqs.NewKeyEncapsulation(kemName)
+		server, _ := oqs.NewKeyEncapsulation(kemName)
+		publicKey, _ := client.GenerateKeyPair()
+		ciphertext, sharedSecretServer, err := server.EncapSecretBuffer(publicKey)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		sharedSecretClient, err := client.DecapSecretBuffer(ciphertext)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		if !bytes.Equal(sharedSecretClient.Bytes(), sharedSecretServer.Bytes()) {
+			t.Errorf("%s: shared secrets do not coincide", kemName)
+		}
+
+		// The content of a buffer that is not destroyed stays valid while the
+		// buffer is reachable.
+		buf, _ := client.DecapSecretBuffer(ciphertext)
+		sharedSecret := buf.Bytes()
+		runtime.GC()
+		runtime.GC()
+		for i := 0; i < 100; i++ {
+			other, _ := oqs.NewSecretBuffer(len(sharedSecret))
+			oqs.RandomBytesInPlace(other.Bytes(), other.Len())
+			other.Destroy()
+		}
+		if !bytes.Equal(sharedSecret, sharedSecretServer.Bytes()) {
+			t.Errorf("%s: shared secret changed after garbage collection", kemName)
+		}
+		runtime.KeepAlive(buf)
+		if _, err := client.DecapSecretBuffer(ciphertext[1:]); !errors.Is(err, oqs.ErrInvalidCiphertextLength) {
+			t.Errorf("%s: expected ErrInvalidCiphertextLength, got %v", kemName, err)
+		}
+		if _, _, err := server.EncapSecretBuffer(publicKey[1:]); !errors.Is(err, oqs.ErrInvalidPublicKeyLength) {
+			t.Errorf("%s: expected ErrInvalidPublicKeyLength, got %v", kemName, err)
+		}
+
+		if client.Details().SecretKeyLocked != sharedSecretClient.Locked() {
+			log.Println("Secret key locking differs from shared secret - ", kemName)
+		}
+		if locked := server.Details().SecretKeyLocked; locked {
+			t.Errorf("%s: secret key locked without a secret key", kemName)
+		}
+
+		// The exported secret key is a copy, which outlives the KEM.
+		secretKey := client.ExportSecretKey()
+		secretKey[0] ^= 1
+		if decapsulated, _ := client.DecapSecret(ciphertext); !bytes.Equal(decapsulated, sharedSecretServer.Bytes()) {
+			t.Errorf("%s: modifying the exported secret key affected the KEM", kemName)
+		}
+		client.Clean()
+		if bytes.Equal(secretKey, make([]byte, len(secretKey))) {
+			t.Errorf("%s: exported secret key was cleansed by Clean", kemName)
+		}
+
+		sharedSecretClient.Destroy()
+		sharedSecretServer.Destroy()
+		server.Clean()
+	}
+}
@@ -0,0 +1,138 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// secretKeyHolder is implemented by KeyEncapsulation and Signature.
+type secretKeyHolder interface {
+	ExportSecretKey() []byte
+	ExportSecretKeyTo(dst []byte) (int, error)
+	Clean()
+}
+
+// testSecretKeyOwnership tests the aliasing guarantees of a KEM or signature
+// created by newHolder from a copy of secretKey.
+func testSecretKeyOwnership(name string, secretKey []byte,
+	newHolder func(oqs.Option) (secretKeyHolder, error), t *testing.T,
+) {
+	// WithSecretKey copies the secret key on import.
+	imported := append([]byte(nil), secretKey...)
+	holder, err := newHolder(oqs.WithSecretKey(imported))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	imported[0] ^= 1
+	if !bytes.Equal(holder.ExportSecretKey(), secretKey) {
+		t.Errorf("%s: modifying the imported secret key affected the holder", name)
+	}
+	imported[0] ^= 1
+
+	// ExportSecretKey copies the secret key on export.
+	exported := holder.ExportSecretKey()
+	exported[0] ^= 1
+	if !bytes.Equal(holder.ExportSecretKey(), secretKey) {
+		t.Errorf("%s: modifying the exported secret key affected the holder", name)
+	}
+
+	dst := make([]byte, len(secretKey)+1)
+	if n, err := holder.ExportSecretKeyTo(dst); err != nil || n != len(secretKey) ||
+		!bytes.Equal(dst[:n], secretKey) {
+		t.Errorf("%s: ExportSecretKeyTo returned %d, %v", name, n, err)
+	}
+	if _, err := holder.ExportSecretKeyTo(dst[:len(secretKey)-1]); !errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("%s: expected io.ErrShortBuffer, got %v", name, err)
+	}
+
+	// Clean leaves the caller's buffers untouched.
+	holder.Clean()
+	if !bytes.Equal(imported, secretKey) {
+		t.Errorf("%s: Clean cleansed the imported secret key", name)
+	}
+	if _, err := holder.ExportSecretKeyTo(dst); !errors.Is(err, oqs.ErrNotInitialized) {
+		t.Errorf("%s: expected ErrNotInitialized, got %v", name, err)
+	}
+
+	// WithBorrowedSecretKey uses the secret key in place.
+	holder, err = newHolder(oqs.WithBorrowedSecretKey(imported))
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	imported[0] ^= 1
+	if !bytes.Equal(holder.ExportSecretKey(), imported) {
+		t.Errorf("%s: borrowed secret key was copied", name)
+	}
+	imported[0] ^= 1
+	holder.Clean()
+	if !bytes.Equal(imported, secretKey) {
+		t.Errorf("%s: Clean cleansed the borrowed secret key", name)
+	}
+
+	// A borrowed secret key of the wrong length is rejected up front.
+	if _, err := newHolder(oqs.WithBorrowedSecretKey(imported[1:])); !errors.Is(err, oqs.ErrInvalidSecretKeyLength) {
+		t.Errorf("%s: expected ErrInvalidSecretKeyLength, got %v", name, err)
+	}
+
+	holder, _ = newHolder(nil)
+	if _, err := holder.ExportSecretKeyTo(dst); !errors.Is(err, oqs.ErrNoSecretKey) {
+		t.Errorf("%s: expected ErrNoSecretKey, got %v", name, err)
+	}
+	holder.Clean()
+}
+
+// TestKeyEncapsulationSecretKeyOwnership tests the aliasing guarantees of the
+// secret keys of all enabled KEMs.
+func TestKeyEncapsulationSecretKeyOwnership(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		log.Println("Secret key ownership - ", kemName)
+		client, _ := oqs.NewKeyEncapsulation(kemName)
+		publicKey, _ := client.GenerateKeyPair()
+		secretKey := client.ExportSecretKey()
+		client.Clean()
+		testSecretKeyOwnership(kemName, secretKey, func(opt oqs.Option) (secretKeyHolder, error) {
+			return oqs.NewKeyEncapsulation(kemName, opt)
+		}, t)
+
+		// The imported copy survives the cleansing of the caller's buffer.
+		imported := append([]byte(nil), secretKey...)
+		client, _ = oqs.NewKeyEncapsulation(kemName, oqs.WithSecretKey(imported))
+		oqs.MemCleanse(imported)
+		ciphertext, sharedSecret, _ := client.EncapSecret(publicKey)
+		if decapsulated, _ := client.DecapSecret(ciphertext); !bytes.Equal(decapsulated, sharedSecret) {
+			t.Errorf("%s: shared secrets do not coincide", kemName)
+		}
+		client.Clean()
+	}
+}
+
+// TestSignatureSecretKeyOwnership tests the aliasing guarantees of the secret
+// keys of all enabled signatures.
+func TestSignatureSecretKeyOwnership(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("Secret key ownership - ", sigName)
+		signer, _ := oqs.NewSignature(sigName)
+		publicKey, _ := signer.GenerateKeyPair()
+		secretKey := signer.ExportSecretKey()
+		signer.Clean()
+		testSecretKeyOwnership(sigName, secretKey, func(opt oqs.Option) (secretKeyHolder, error) {
+			return oqs.NewSignature(sigName, opt)
+		}, t)
+
+		// The imported copy survives the cleansing of the caller's buffer.
+		imported := append([]byte(nil), secretKey...)
+		signer, _ = oqs.NewSignature(sigName, oqs.WithSecretKey(imported))
+		oqs.MemCleanse(imported)
+		signature, _ := signer.Sign(msg)
+		if isValid, _ := signer.Verify(msg, signature, publicKey); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		signer.Clean()
+	}
+}
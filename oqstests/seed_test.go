@@ -0,0 +1,171 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestKeyEncapsulationFromSeed tests the deterministic key generation and
+// encapsulation of all enabled KEMs that support them.
+func TestKeyEncapsulationFromSeed(t *testing.T) {
+	for _, kemName := range oqs.EnabledKEMs() {
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		details := kem.Details()
+		if details.LengthKeypairSeed == 0 {
+			if _, err := kem.GenerateKeyPairFromSeed(nil); !errors.Is(err, oqs.ErrDerandNotSupported) {
+				t.Errorf("%s: expected ErrDerandNotSupported, got %v", kemName, err)
+			}
+			kem.Clean()
+			continue
+		}
+		log.Println("KEM from seed - ", kemName)
+		seed := oqs.RandomBytes(details.LengthKeypairSeed)
+		if _, err := kem.GenerateKeyPairFromSeed(seed[1:]); !errors.Is(err, oqs.ErrInvalidSeedLength) {
+			t.Errorf("%s: expected ErrInvalidSeedLength, got %v", kemName, err)
+		}
+		publicKey, err := kem.GenerateKeyPairFromSeed(seed)
+		if err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		other, _ := oqs.NewKeyEncapsulation(kemName)
+		otherPublicKey, _ := other.GenerateKeyPairFromSeed(seed)
+		if !bytes.Equal(publicKey, otherPublicKey) ||
+			!bytes.Equal(kem.ExportSecretKey(), other.ExportSecretKey()) {
+			t.Errorf("%s: key pairs generated from the same seed do not coincide", kemName)
+		}
+
+		if details.LengthEncapsSeed != 0 {
+			m := oqs.RandomBytes(details.LengthEncapsSeed)
+			ciphertext, sharedSecret, err := kem.EncapSecretDeterministic(publicKey, m)
+			if err != nil {
+				t.Fatalf("%s: %v", kemName, err)
+			}
+			otherCiphertext, otherSharedSecret, _ := other.EncapSecretDeterministic(publicKey, m)
+			if !bytes.Equal(ciphertext, otherCiphertext) ||
+				!bytes.Equal(sharedSecret, otherSharedSecret) {
+				t.Errorf("%s: deterministic encapsulations do not coincide", kemName)
+			}
+			if decapsulated, _ := kem.DecapSecret(ciphertext); !bytes.Equal(sharedSecret, decapsulated) {
+				t.Errorf("%s: shared secrets do not coincide", kemName)
+			}
+			if _, _, err := kem.EncapSecretDeterministic(publicKey, m[1:]); !errors.Is(err, oqs.ErrInvalidSeedLength) {
+				t.Errorf("%s: expected ErrInvalidSeedLength, got %v", kemName, err)
+			}
+		}
+		other.Clean()
+		kem.Clean()
+	}
+}
+
+// TestSignatureFromSeed tests the deterministic key generation of all enabled
+// signatures that support it.
+func TestSignatureFromSeed(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range oqs.EnabledSigs() {
+		signer, _ := oqs.NewSignature(sigName)
+		details := signer.Details()
+		if details.LengthKeypairSeed == 0 {
+			if _, err := signer.GenerateKeyPairFromSeed(nil); !errors.Is(err, oqs.ErrDerandNotSupported) {
+				t.Errorf("%s: expected ErrDerandNotSupported, got %v", sigName, err)
+			}
+			signer.Clean()
+			continue
+		}
+		log.Println("Signature from seed - ", sigName)
+		seed := oqs.RandomBytes(details.LengthKeypairSeed)
+		if _, err := signer.GenerateKeyPairFromSeed(append(seed, 0)); !errors.Is(err, oqs.ErrInvalidSeedLength) {
+			t.Errorf("%s: expected ErrInvalidSeedLength, got %v", sigName, err)
+		}
+		publicKey, err := signer.GenerateKeyPairFromSeed(seed)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		other, _ := oqs.NewSignature(sigName)
+		otherPublicKey, _ := other.GenerateKeyPairFromSeed(seed)
+		if !bytes.Equal(publicKey, otherPublicKey) ||
+			!bytes.Equal(signer.ExportSecretKey(), other.ExportSecretKey()) {
+			t.Errorf("%s: key pairs generated from the same seed do not coincide", sigName)
+		}
+		signature, _ := other.Sign(msg)
+		if isValid, _ := signer.Verify(msg, signature, publicKey); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		// The RNG is unaffected by the seeded key generation.
+		if fresh, _ := other.GenerateKeyPair(); bytes.Equal(fresh, publicKey) {
+			t.Errorf("%s: random key pair coincides with the seeded one", sigName)
+		}
+		other.Clean()
+		signer.Clean()
+	}
+}
+
+// TestPKIXSeed tests the seed and both PKCS#8 private key formats.
+func TestPKIXSeed(t *testing.T) {
+	var keys []any
+	for _, kemName := range oqs.EnabledKEMs() {
+		if _, err := oqs.AlgorithmOID(kemName); err != nil {
+			continue
+		}
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		if kem.Details().LengthKeypairSeed == 0 {
+			kem.Clean()
+			continue
+		}
+		if _, err := kem.GenerateKeyPairFromSeed(oqs.RandomBytes(kem.Details().LengthKeypairSeed)); err != nil {
+			t.Fatalf("%s: %v", kemName, err)
+		}
+		keys = append(keys, kem)
+	}
+	for _, sigName := range oqs.EnabledSigs() {
+		if _, err := oqs.AlgorithmOID(sigName); err != nil {
+			continue
+		}
+		sig, _ := oqs.NewSignature(sigName)
+		if sig.Details().LengthKeypairSeed == 0 {
+			sig.Clean()
+			continue
+		}
+		if _, err := sig.GenerateKeyPairFromSeed(oqs.RandomBytes(sig.Details().LengthKeypairSeed)); err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		keys = append(keys, sig)
+	}
+
+	for _, key := range keys {
+		expanded, _ := oqs.MarshalPKCS8PrivateKey(key)
+		for _, format := range []oqs.PrivateKeyFormat{oqs.PrivateKeySeed, oqs.PrivateKeyBoth} {
+			der, err := oqs.MarshalPKCS8PrivateKeyFormat(key, format)
+			if err != nil {
+				t.Fatalf("%v: %v", key, err)
+			}
+			if len(der) >= len(expanded) && format == oqs.PrivateKeySeed {
+				t.Errorf("%v: seed format is not shorter than the expanded one", key)
+			}
+			parsed, err := oqs.ParsePKCS8PrivateKey(der)
+			if err != nil {
+				t.Fatalf("%v: %v", key, err)
+			}
+			// Round trip through the same format.
+			reencoded, err := oqs.MarshalPKCS8PrivateKeyFormat(parsed, format)
+			if err != nil || !bytes.Equal(der, reencoded) {
+				t.Errorf("%v: re-encoded private key does not coincide", key)
+			}
+			if reexpanded, _ := oqs.MarshalPKCS8PrivateKey(parsed); !bytes.Equal(expanded, reexpanded) {
+				t.Errorf("%v: expanded private keys do not coincide", key)
+			}
+			parsed.(interface{ Clean() }).Clean()
+		}
+
+		// The expanded key of the both form must match the seed.
+		der, _ := oqs.MarshalPKCS8PrivateKeyFormat(key, oqs.PrivateKeyBoth)
+		der[len(der)-1] ^= 1
+		if _, err := oqs.ParsePKCS8PrivateKey(der); !errors.Is(err, oqs.ErrInvalidSecretKey) {
+			t.Errorf("%v: expected ErrInvalidSecretKey, got %v", key, err)
+		}
+		key.(interface{ Clean() }).Clean()
+	}
+}
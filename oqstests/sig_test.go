@@ -1,6 +1,7 @@
 package oqstests
 
 import (
+	"errors"
 	"log"
 	"runtime"
 	"sync"
@@ -283,3 +284,105 @@ func TestUnsupportedSignature(t *testing.T) {
 		t.Fatal("Unsupported signature should have emitted an error")
 	}
 }
+
+// TestNewSignature tests the constructor-style API and that Clean can be
+// safely invoked more than once.
+func TestNewSignature(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range oqs.EnabledSigs() {
+		signer, err := oqs.NewSignature(sigName)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		pubKey, _ := signer.GenerateKeyPair()
+		signature, _ := signer.Sign(msg)
+		verifier, err := oqs.NewSignature(sigName)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		isValid, _ := verifier.Verify(msg, signature, pubKey)
+		if !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		verifier.Clean()
+		signer.Clean()
+		signer.Clean()
+	}
+	if _, err := oqs.NewSignature("unsupported_sig"); err == nil {
+		t.Errorf("Unsupported signature should have emitted an error")
+	}
+}
+
+// TestSignatureErrors tests that failures are reported as typed errors.
+func TestSignatureErrors(t *testing.T) {
+	var uninitialized oqs.Signature
+	if _, err := uninitialized.Sign(nil); !errors.Is(err, oqs.ErrNotInitialized) {
+		t.Errorf("Expected ErrNotInitialized, got %v", err)
+	}
+	if _, err := oqs.NewSignature("unsupported_sig"); !errors.Is(err, oqs.ErrAlgorithmNotSupported) {
+		t.Errorf("Expected ErrAlgorithmNotSupported, got %v", err)
+	}
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range oqs.EnabledSigs() {
+		sig, _ := oqs.NewSignature(sigName)
+		details := sig.Details()
+		if _, err := sig.Sign(msg); !errors.Is(err, oqs.ErrNoSecretKey) {
+			t.Errorf("%s: expected ErrNoSecretKey, got %v", sigName, err)
+		}
+		pubKey, _ := sig.GenerateKeyPair()
+		_, err := sig.Verify(msg, make([]byte, details.MaxLengthSignature+1), pubKey)
+		var oqsErr *oqs.Error
+		if !errors.As(err, &oqsErr) || oqsErr.Alg != sigName ||
+			!errors.Is(err, oqs.ErrInvalidSignatureLength) {
+			t.Errorf("%s: expected ErrInvalidSignatureLength, got %v", sigName, err)
+		}
+		if !details.SigWithCtxSupport {
+			if _, err := sig.SignWithCtxStr(msg, []byte("context")); !errors.Is(err, oqs.ErrContextNotSupported) {
+				t.Errorf("%s: expected ErrContextNotSupported, got %v", sigName, err)
+			}
+		}
+		sig.Clean()
+	}
+}
+
+// TestSignatureEmptyInputs tests signing and verifying empty messages, with
+// and without an empty context string, as allowed by FIPS 204 and FIPS 205.
+func TestSignatureEmptyInputs(t *testing.T) {
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("Empty inputs - ", sigName)
+		signer, _ := oqs.NewSignature(sigName)
+		pubKey, _ := signer.GenerateKeyPair()
+		for _, msg := range [][]byte{nil, {}} {
+			signature, err := signer.Sign(msg)
+			if err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+			if isValid, err := signer.Verify(msg, signature, pubKey); err != nil || !isValid {
+				t.Errorf("%s: empty message verification failed: %v", sigName, err)
+			}
+			if isValid, _ := signer.Verify([]byte("x"), signature, pubKey); isValid {
+				t.Errorf("%s: non-empty message verification should have failed", sigName)
+			}
+			// An empty context string is the same as none
+			signature, err = signer.SignWithCtxStr(msg, []byte{})
+			if err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+			if isValid, err := signer.VerifyWithCtxStr(msg, signature, nil, pubKey); err != nil || !isValid {
+				t.Errorf("%s: empty context verification failed: %v", sigName, err)
+			}
+			if results := signer.VerifyBatch([]oqs.VerifyItem{{
+				Message: msg, Signature: signature, PublicKey: pubKey,
+			}}); results[0] != nil {
+				t.Errorf("%s: empty message batch verification failed: %v", sigName, results[0])
+			}
+		}
+		if isValid, err := signer.Verify(nil, nil, pubKey); err != nil || isValid {
+			t.Errorf("%s: empty signature verification returned %v, %v", sigName, isValid, err)
+		}
+		if isValid, err := signer.VerifyWithCtxStr(nil, []byte{}, nil, pubKey); err != nil || isValid {
+			t.Errorf("%s: empty signature verification returned %v, %v", sigName, isValid, err)
+		}
+		signer.Clean()
+	}
+}
@@ -0,0 +1,67 @@
+package oqstests
+
+import (
+	"crypto"
+	"errors"
+	"log"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// TestSigPrivateKeyCryptoSigner tests the crypto.Signer implementation of all
+// enabled signatures.
+func TestSigPrivateKeyCryptoSigner(t *testing.T) {
+	msg := []byte("This is our favourite message to sign")
+	for _, sigName := range oqs.EnabledSigs() {
+		log.Println("crypto.Signer - ", sigName)
+		priv, err := oqs.GenerateSigKey(sigName)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		var signer crypto.Signer = priv
+		signature, err := signer.Sign(nil, msg, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		pub := signer.Public().(*oqs.SigPublicKey)
+		if isValid, _ := pub.Verify(msg, signature, nil); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+
+		imported, err := oqs.NewSigPrivateKey(sigName, priv.ExportSecretKey(), pub.Bytes())
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if !imported.Equal(priv) || !imported.Public().(*oqs.SigPublicKey).Equal(pub) {
+			t.Errorf("%s: imported key pair should be equal", sigName)
+		}
+		imported.Clean()
+		other, _ := oqs.GenerateSigKey(sigName)
+		if _, err := oqs.NewSigPrivateKey(sigName, priv.ExportSecretKey(), other.Public().(*oqs.SigPublicKey).Bytes()); !errors.Is(err, oqs.ErrKeyPairMismatch) {
+			t.Errorf("%s: expected ErrKeyPairMismatch, got %v", sigName, err)
+		}
+		other.Clean()
+
+		if _, err := signer.Sign(nil, msg, crypto.SHA256); !errors.Is(err, oqs.ErrUnsupportedHash) {
+			t.Errorf("%s: expected ErrUnsupportedHash, got %v", sigName, err)
+		}
+
+		sig, _ := oqs.NewSignature(sigName)
+		if sig.Details().SigWithCtxSupport {
+			opts := &oqs.SignerOpts{Context: []byte("context")}
+			signature, err := signer.Sign(nil, msg, opts)
+			if err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+			if isValid, _ := pub.Verify(msg, signature, opts); !isValid {
+				t.Errorf("%s: signature verification with context failed", sigName)
+			}
+			if isValid, _ := pub.Verify(msg, signature, nil); isValid {
+				t.Errorf("%s: signature verification without context should have failed", sigName)
+			}
+		}
+		sig.Clean()
+		priv.Clean()
+	}
+}
@@ -0,0 +1,251 @@
+package oqstests
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+)
+
+// failingStore is a SecretKeyStore that fails after a number of successful
+// invocations; a negative number never fails.
+type failingStore struct {
+	remaining int
+	stored    []byte
+}
+
+// Store implements oqs.SecretKeyStore.
+func (s *failingStore) Store(secretKey []byte) error {
+	if s.remaining == 0 {
+		return errors.New("disk full")
+	}
+	s.remaining--
+	s.stored = append(s.stored[:0], secretKey...)
+	return nil
+}
+
+// newStatefulSignature creates a stateful signature with a fresh key pair, or
+// returns nil if liboqs does not allow stateful key generation.
+func newStatefulSignature(sigName string, store oqs.SecretKeyStore,
+	t *testing.T,
+) (*oqs.StatefulSignature, []byte) {
+	signer, err := oqs.NewStatefulSignature(sigName, store)
+	if err != nil {
+		t.Fatalf("%s: %v", sigName, err)
+	}
+	pubKey, err := signer.GenerateKeyPair()
+	if errors.Is(err, oqs.ErrLiboqsFailure) {
+		log.Println("Stateful key generation is not enabled - ", sigName)
+		signer.Clean()
+		return nil, nil
+	}
+	if err != nil {
+		t.Fatalf("%s: %v", sigName, err)
+	}
+	return signer, pubKey
+}
+
+// TestStatefulSignature tests signing, verification and the durable state
+// management of all enabled stateful signatures.
+func TestStatefulSignature(t *testing.T) {
+	msg := []byte("This is our favourite firmware image to sign")
+	for _, sigName := range oqs.EnabledStatefulSigs() {
+		log.Println("Stateful - ", sigName)
+		path := filepath.Join(t.TempDir(), "key")
+		store, err := oqs.NewFileSecretKeyStore(path)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if _, err := oqs.NewFileSecretKeyStore(path); !errors.Is(err, oqs.ErrSecretKeyStoreLocked) {
+			t.Errorf("%s: expected ErrSecretKeyStoreLocked, got %v", sigName, err)
+		}
+		signer, pubKey := newStatefulSignature(sigName, store, t)
+		if signer == nil {
+			store.Close()
+			continue
+		}
+		total, _ := signer.SigsTotal()
+
+		var signatures [][]byte
+		for i := 0; i < 2; i++ {
+			signature, err := signer.Sign(msg)
+			if err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+			if isValid, _ := signer.Verify(msg, signature, pubKey); !isValid {
+				t.Errorf("%s: signature verification failed", sigName)
+			}
+			persisted, _ := store.Load()
+			exported, _ := signer.ExportSecretKey()
+			if !bytes.Equal(persisted, exported) {
+				t.Errorf("%s: state was not persisted before the signature was released", sigName)
+			}
+			signatures = append(signatures, signature)
+		}
+		if remaining, _ := signer.SigsRemaining(); remaining != total-2 {
+			t.Errorf("%s: expected %d remaining signatures, got %d", sigName, total-2, remaining)
+		}
+		if bytes.Equal(signatures[0], signatures[1]) {
+			t.Errorf("%s: one-time key was reused", sigName)
+		}
+		if _, err := signer.GenerateKeyPair(); !errors.Is(err, oqs.ErrSecretKeyExists) {
+			t.Errorf("%s: expected ErrSecretKeyExists, got %v", sigName, err)
+		}
+		signer.Clean()
+
+		// A fresh signature must not overwrite the persisted state either.
+		signer, _ = oqs.NewStatefulSignature(sigName, store)
+		if _, err := signer.GenerateKeyPair(); !errors.Is(err, oqs.ErrSecretKeyExists) {
+			t.Errorf("%s: expected ErrSecretKeyExists, got %v", sigName, err)
+		}
+		signer.Clean()
+
+		// Resume from the persisted state after a "restart".
+		persisted, _ := store.Load()
+		signer, err = oqs.NewStatefulSignature(sigName, store, oqs.WithSecretKey(persisted))
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if remaining, _ := signer.SigsRemaining(); remaining != total-2 {
+			t.Errorf("%s: restored state has %d remaining signatures", sigName, remaining)
+		}
+		signature, _ := signer.Sign(msg)
+		for _, previous := range signatures {
+			if bytes.Equal(signature, previous) {
+				t.Errorf("%s: one-time key was reused after restart", sigName)
+			}
+		}
+		if signature, err := signer.Sign(nil); err != nil {
+			t.Errorf("%s: %v", sigName, err)
+		} else if isValid, _ := signer.Verify(nil, signature, pubKey); !isValid {
+			t.Errorf("%s: empty message verification failed", sigName)
+		}
+		signer.Clean()
+		store.Close()
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s: %v", sigName, err)
+		}
+	}
+}
+
+// TestStatefulSignatureFile tests the stateful signatures persisted to a key
+// file by the default store.
+func TestStatefulSignatureFile(t *testing.T) {
+	msg := []byte("This is our favourite firmware image to sign")
+	for _, sigName := range oqs.EnabledStatefulSigs() {
+		log.Println("Stateful file - ", sigName)
+		path := filepath.Join(t.TempDir(), "key")
+		signer, err := oqs.NewStatefulSignatureFile(sigName, path)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		pubKey, err := signer.GenerateKeyPair()
+		if errors.Is(err, oqs.ErrLiboqsFailure) {
+			log.Println("Stateful key generation is not enabled - ", sigName)
+			signer.Clean()
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		total, _ := signer.SigsTotal()
+		signature, _ := signer.Sign(msg)
+		if _, err := oqs.NewStatefulSignatureFile(sigName, path); !errors.Is(err, oqs.ErrSecretKeyStoreLocked) {
+			t.Errorf("%s: expected ErrSecretKeyStoreLocked, got %v", sigName, err)
+		}
+		signer.Clean()
+
+		// Clean releases the key file, whose state is resumed.
+		signer, err = oqs.NewStatefulSignatureFile(sigName, path)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if remaining, _ := signer.SigsRemaining(); remaining != total-1 {
+			t.Errorf("%s: resumed state has %d remaining signatures", sigName, remaining)
+		}
+		if _, err := signer.GenerateKeyPair(); !errors.Is(err, oqs.ErrSecretKeyExists) {
+			t.Errorf("%s: expected ErrSecretKeyExists, got %v", sigName, err)
+		}
+		resumed, _ := signer.Sign(msg)
+		if bytes.Equal(resumed, signature) {
+			t.Errorf("%s: one-time key was reused after restart", sigName)
+		}
+		if isValid, _ := signer.Verify(msg, resumed, pubKey); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		signer.Clean()
+	}
+}
+
+// TestStatefulSignatureStoreFailure tests that no signature is released when
+// the secret key state can not be persisted.
+func TestStatefulSignatureStoreFailure(t *testing.T) {
+	msg := []byte("This is our favourite firmware image to sign")
+	for _, sigName := range oqs.EnabledStatefulSigs() {
+		log.Println("Stateful store failure - ", sigName)
+		if _, err := oqs.NewStatefulSignature(sigName, nil); !errors.Is(err, oqs.ErrNilSecretKeyStore) {
+			t.Errorf("%s: expected ErrNilSecretKeyStore, got %v", sigName, err)
+		}
+		store := &failingStore{remaining: 2}
+		signer, pubKey := newStatefulSignature(sigName, store, t)
+		if signer == nil {
+			continue
+		}
+		if _, err := signer.Sign(msg); err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		stored := append([]byte(nil), store.stored...)
+		signature, err := signer.Sign(msg)
+		if signature != nil || !errors.Is(err, oqs.ErrSecretKeyStore) {
+			t.Errorf("%s: expected ErrSecretKeyStore and no signature, got %v", sigName, err)
+		}
+		if !bytes.Equal(stored, store.stored) {
+			t.Errorf("%s: persisted state was modified by a failed store", sigName)
+		}
+		// The one-time key consumed by the failed signature is never reused.
+		store.remaining = 1
+		signature, _ = signer.Sign(msg)
+		if isValid, _ := signer.Verify(msg, signature, pubKey); !isValid {
+			t.Errorf("%s: signature verification failed", sigName)
+		}
+		signer.Clean()
+		if _, err := signer.Sign(msg); !errors.Is(err, oqs.ErrNotInitialized) {
+			t.Errorf("%s: expected ErrNotInitialized, got %v", sigName, err)
+		}
+	}
+}
+
+// TestStatefulSignatureExhausted tests that a key refuses to sign once all
+// its one-time keys are used.
+func TestStatefulSignatureExhausted(t *testing.T) {
+	msg := []byte("This is our favourite firmware image to sign")
+	for _, sigName := range oqs.EnabledStatefulSigs() {
+		store := &failingStore{remaining: -1}
+		signer, _ := newStatefulSignature(sigName, store, t)
+		if signer == nil {
+			continue
+		}
+		if total, _ := signer.SigsTotal(); total > 32 {
+			signer.Clean()
+			continue
+		}
+		log.Println("Stateful exhausted - ", sigName)
+		for {
+			remaining, _ := signer.SigsRemaining()
+			if remaining == 0 {
+				break
+			}
+			if _, err := signer.Sign(msg); err != nil {
+				t.Fatalf("%s: %v", sigName, err)
+			}
+		}
+		if _, err := signer.Sign(msg); !errors.Is(err, oqs.ErrSignaturesExhausted) {
+			t.Errorf("%s: expected ErrSignaturesExhausted, got %v", sigName, err)
+		}
+		signer.Clean()
+	}
+}
@@ -0,0 +1,325 @@
+package oqstests
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"log"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/open-quantum-safe/liboqs-go/oqs"
+	"github.com/open-quantum-safe/liboqs-go/oqs/x509pq"
+)
+
+// x509pqSigner is implemented by *oqs.SigPrivateKey and
+// *oqs.CompositePrivateKey.
+type x509pqSigner interface {
+	crypto.Signer
+	Clean()
+}
+
+// generateX509PQSigner generates a signing key for a plain or composite
+// signature, and returns nil if sigName is neither enabled nor has an OID.
+func generateX509PQSigner(sigName string) x509pqSigner {
+	if _, err := oqs.AlgorithmOID(sigName); err != nil {
+		return nil
+	}
+	if key, err := oqs.GenerateSigKey(sigName); err == nil {
+		return key
+	}
+	if key, err := oqs.GenerateCompositeKey(sigName); err == nil {
+		return key
+	}
+	return nil
+}
+
+// createX509PQCertificate creates and parses a certificate.
+func createX509PQCertificate(template, parent *x509pq.Certificate,
+	pub crypto.PublicKey, priv crypto.Signer, t *testing.T,
+) *x509pq.Certificate {
+	der, err := x509pq.CreateCertificate(template, parent, pub, priv)
+	if err != nil {
+		t.Fatalf("%s: %v", template.Subject.CommonName, err)
+	}
+	cert, err := x509pq.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("%s: %v", template.Subject.CommonName, err)
+	}
+	return cert
+}
+
+// TestX509PQChain issues a root, an intermediate and a leaf certificate for
+// all enabled signatures that have an OID, and verifies the chain.
+func TestX509PQChain(t *testing.T) {
+	now := time.Now()
+	for _, sigName := range append(oqs.EnabledSigs(), oqs.SupportedCompositeSigs()...) {
+		key := generateX509PQSigner(sigName)
+		if key == nil {
+			continue
+		}
+		log.Println("X509PQ - ", sigName)
+		rootTemplate := &x509pq.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: sigName + " root"},
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		root := createX509PQCertificate(rootTemplate, rootTemplate,
+			key.Public(), key, t)
+		if root.SignatureAlgorithm != sigName ||
+			root.PublicKeyAlgorithm != sigName {
+			t.Errorf("%s: unexpected algorithms %s and %s", sigName,
+				root.SignatureAlgorithm, root.PublicKeyAlgorithm)
+		}
+		if err := root.CheckSignatureFrom(root); err != nil {
+			t.Errorf("%s: self-signature: %v", sigName, err)
+		}
+
+		interKey := generateX509PQSigner(sigName)
+		interTemplate := &x509pq.Certificate{
+			SerialNumber:          big.NewInt(2),
+			Subject:               pkix.Name{CommonName: sigName + " intermediate"},
+			NotBefore:             now.Add(-time.Hour),
+			NotAfter:              now.Add(time.Hour),
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+			MaxPathLenZero:        true,
+		}
+		inter := createX509PQCertificate(interTemplate, root,
+			interKey.Public(), key, t)
+		if !inter.MaxPathLenZero || inter.MaxPathLen != 0 {
+			t.Errorf("%s: path length constraint was lost", sigName)
+		}
+
+		leafKey := generateX509PQSigner(sigName)
+		leafTemplate := &x509pq.Certificate{
+			SerialNumber: big.NewInt(3),
+			Subject:      pkix.Name{CommonName: sigName + " leaf"},
+			NotBefore:    now.Add(-time.Minute),
+			NotAfter:     now.Add(time.Minute),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			DNSNames:     []string{"pq.example.com"},
+		}
+		leaf := createX509PQCertificate(leafTemplate, inter,
+			leafKey.Public(), interKey, t)
+		if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "pq.example.com" {
+			t.Errorf("%s: unexpected DNS names %v", sigName, leaf.DNSNames)
+		}
+		if leaf.KeyUsage != x509.KeyUsageDigitalSignature {
+			t.Errorf("%s: unexpected key usage %v", sigName, leaf.KeyUsage)
+		}
+
+		opts := x509pq.VerifyOptions{
+			Roots:         []*x509pq.Certificate{root},
+			Intermediates: []*x509pq.Certificate{inter},
+		}
+		chain, err := leaf.Verify(opts)
+		if err != nil {
+			t.Errorf("%s: %v", sigName, err)
+		} else if len(chain) != 3 || !chain[0].Equal(leaf) ||
+			!chain[1].Equal(inter) || !chain[2].Equal(root) {
+			t.Errorf("%s: unexpected chain", sigName)
+		}
+
+		if _, err := leaf.Verify(x509pq.VerifyOptions{
+			Roots: []*x509pq.Certificate{root},
+		}); !errors.Is(err, x509pq.ErrUnknownAuthority) {
+			t.Errorf("%s: expected ErrUnknownAuthority, got %v", sigName, err)
+		}
+		expired := opts
+		expired.CurrentTime = now.Add(time.Hour / 2)
+		if _, err := leaf.Verify(expired); !errors.Is(err, x509pq.ErrExpired) {
+			t.Errorf("%s: expected ErrExpired, got %v", sigName, err)
+		}
+		if err := inter.CheckSignatureFrom(leaf); !errors.Is(err, x509pq.ErrNotAuthorizedToSign) {
+			t.Errorf("%s: expected ErrNotAuthorizedToSign, got %v", sigName, err)
+		}
+
+		tampered := *leaf
+		tampered.Signature = append([]byte(nil), leaf.Signature...)
+		tampered.Signature[0] ^= 1
+		if err := tampered.CheckSignatureFrom(inter); !errors.Is(err, x509pq.ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", sigName, err)
+		}
+
+		// A CA with a zero path length must not issue another CA.
+		subTemplate := *interTemplate
+		subTemplate.Subject.CommonName = sigName + " sub-intermediate"
+		sub := createX509PQCertificate(&subTemplate, inter,
+			leafKey.Public(), interKey, t)
+		leaf2 := createX509PQCertificate(leafTemplate, sub,
+			interKey.Public(), leafKey, t)
+		if _, err := leaf2.Verify(x509pq.VerifyOptions{
+			Roots:         []*x509pq.Certificate{root},
+			Intermediates: []*x509pq.Certificate{inter, sub},
+		}); !errors.Is(err, x509pq.ErrTooManyIntermediates) {
+			t.Errorf("%s: expected ErrTooManyIntermediates, got %v", sigName, err)
+		}
+
+		leafKey.Clean()
+		interKey.Clean()
+		key.Clean()
+	}
+}
+
+// TestX509PQVerifyConstraints tests that Verify rejects unhandled critical
+// extensions and incompatible extended key usages, and accepts a trusted
+// self-signed leaf.
+func TestX509PQVerifyConstraints(t *testing.T) {
+	var key x509pqSigner
+	for _, sigName := range oqs.EnabledSigs() {
+		if key = generateX509PQSigner(sigName); key != nil {
+			break
+		}
+	}
+	if key == nil {
+		t.Skip("no signature with an OID is enabled")
+	}
+	defer key.Clean()
+	now := time.Now()
+	rootTemplate := &x509pq.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	root := createX509PQCertificate(rootTemplate, rootTemplate, key.Public(), key, t)
+	opts := x509pq.VerifyOptions{Roots: []*x509pq.Certificate{root}}
+	if chain, err := root.Verify(opts); err != nil || len(chain) != 1 || !chain[0].Equal(root) {
+		t.Errorf("trusted self-signed certificate: %v", err)
+	}
+
+	leafTemplate := &x509pq.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    now.Add(-time.Minute),
+		NotAfter:     now.Add(time.Minute),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	leaf := createX509PQCertificate(leafTemplate, root, key.Public(), key, t)
+	if len(leaf.ExtKeyUsage) != 1 || leaf.ExtKeyUsage[0] != x509.ExtKeyUsageClientAuth {
+		t.Errorf("unexpected extended key usages %v", leaf.ExtKeyUsage)
+	}
+	if _, err := leaf.Verify(opts); !errors.Is(err, x509pq.ErrIncompatibleUsage) {
+		t.Errorf("expected ErrIncompatibleUsage, got %v", err)
+	}
+	for _, usage := range []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageAny} {
+		usageOpts := opts
+		usageOpts.KeyUsages = []x509.ExtKeyUsage{usage}
+		if _, err := leaf.Verify(usageOpts); err != nil {
+			t.Errorf("extended key usage %v: %v", usage, err)
+		}
+	}
+
+	// Name constraints, as any critical extension that is not processed,
+	// must be rejected.
+	for _, oid := range []asn1.ObjectIdentifier{{2, 5, 29, 30}, {1, 2, 3, 4}} {
+		criticalTemplate := *leafTemplate
+		criticalTemplate.ExtKeyUsage = nil
+		criticalTemplate.ExtraExtensions = []pkix.Extension{
+			{Id: oid, Critical: true, Value: []byte{0x30, 0x00}},
+		}
+		critical := createX509PQCertificate(&criticalTemplate, root, key.Public(), key, t)
+		if len(critical.UnhandledCriticalExtensions) != 1 ||
+			!critical.UnhandledCriticalExtensions[0].Equal(oid) {
+			t.Errorf("%v: unexpected unhandled critical extensions %v", oid,
+				critical.UnhandledCriticalExtensions)
+		}
+		if _, err := critical.Verify(opts); !errors.Is(err, x509pq.ErrUnhandledCriticalExtension) {
+			t.Errorf("%v: expected ErrUnhandledCriticalExtension, got %v", oid, err)
+		}
+	}
+}
+
+// TestX509PQKEMCertificate tests certificates that carry a KEM public key.
+func TestX509PQKEMCertificate(t *testing.T) {
+	var key x509pqSigner
+	for _, sigName := range oqs.EnabledSigs() {
+		if key = generateX509PQSigner(sigName); key != nil {
+			break
+		}
+	}
+	if key == nil {
+		t.Skip("no signature with an OID is enabled")
+	}
+	defer key.Clean()
+	for _, kemName := range oqs.EnabledKEMs() {
+		if _, err := oqs.AlgorithmOID(kemName); err != nil {
+			continue
+		}
+		log.Println("X509PQ - ", kemName)
+		kem, _ := oqs.NewKeyEncapsulation(kemName)
+		publicKey, _ := kem.GenerateKeyPair()
+		pub, _ := oqs.NewKEMPublicKey(kemName, publicKey)
+		template := &x509pq.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: kemName},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageKeyEncipherment,
+		}
+		issuer := &x509pq.Certificate{Subject: pkix.Name{CommonName: "issuer"}}
+		cert := createX509PQCertificate(template, issuer, pub, key, t)
+		if cert.PublicKeyAlgorithm != kemName || !pub.Equal(cert.PublicKey) {
+			t.Errorf("%s: public key does not coincide", kemName)
+		}
+		if cert.Issuer.CommonName != "issuer" {
+			t.Errorf("%s: unexpected issuer %v", kemName, cert.Issuer)
+		}
+		kem.Clean()
+	}
+}
+
+// TestX509PQCertificateRequest tests certificate requests for all enabled
+// signatures that have an OID.
+func TestX509PQCertificateRequest(t *testing.T) {
+	for _, sigName := range append(oqs.EnabledSigs(), oqs.SupportedCompositeSigs()...) {
+		key := generateX509PQSigner(sigName)
+		if key == nil {
+			continue
+		}
+		log.Println("X509PQ CSR - ", sigName)
+		der, err := x509pq.CreateCertificateRequest(&x509pq.CertificateRequest{
+			Subject:  pkix.Name{CommonName: sigName, Organization: []string{"OQS"}},
+			DNSNames: []string{"a.example.com", "b.example.com"},
+		}, key)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		req, err := x509pq.ParseCertificateRequest(der)
+		if err != nil {
+			t.Fatalf("%s: %v", sigName, err)
+		}
+		if err := req.CheckSignature(); err != nil {
+			t.Errorf("%s: %v", sigName, err)
+		}
+		if req.Subject.CommonName != sigName || len(req.DNSNames) != 2 ||
+			req.SignatureAlgorithm != sigName {
+			t.Errorf("%s: unexpected request contents", sigName)
+		}
+		if pub, ok := req.PublicKey.(interface {
+			Equal(crypto.PublicKey) bool
+		}); !ok || !pub.Equal(key.Public()) {
+			t.Errorf("%s: public key does not coincide", sigName)
+		}
+		req.Signature[len(req.Signature)-1] ^= 1
+		if err := req.CheckSignature(); !errors.Is(err, x509pq.ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", sigName, err)
+		}
+		key.Clean()
+	}
+	if _, err := x509pq.ParseCertificateRequest([]byte{0x30, 0x00}); !errors.Is(err, x509pq.ErrMalformed) {
+		t.Errorf("expected ErrMalformed, got %v", err)
+	}
+}